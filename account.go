@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/auth"
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/jamistoso/gator/internal/output"
+	"golang.org/x/term"
+)
+
+const (
+	tokenUsage = "usage: gator token create <name> | list | revoke <id|name>"
+	feverUsage = "usage: gator fever enable | disable"
+)
+
+// askNewPassword asks for a new password, twice on a terminal.
+func askNewPassword() (string, error) {
+	password, err := readPassword("Password: ")
+	if err != nil {
+		return "", err
+	}
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		again, err := readPassword("Repeat password: ")
+		if err != nil {
+			return "", err
+		}
+		if again != password {
+			return "", errors.New("passwords don't match")
+		}
+	}
+	return password, nil
+}
+
+// readNewPassword asks for a new password and returns its hash.
+func readNewPassword() (sql.NullString, error) {
+	password, err := askNewPassword()
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: hash, Valid: true}, nil
+}
+
+// checkPassword asks for the user's password and fails unless it's right.
+// Users created before passwords were added have none and always fail, so
+// nobody can take them over by name alone.
+func checkPassword(user database.User) error {
+	if !user.PasswordHash.Valid {
+		return fmt.Errorf("%s has no password yet; while logged in as them, set one with 'gator passwd'", user.Name)
+	}
+	password, err := readPassword("Password: ")
+	if err != nil {
+		return err
+	}
+	return auth.CheckPassword(user.PasswordHash.String, password)
+}
+
+func handlerPasswd(s *state, cmd command, currentUser database.User) error {
+	// users without a password can only set one while still logged in
+	if currentUser.PasswordHash.Valid {
+		if err := checkPassword(currentUser); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(os.Stderr, "New password for", currentUser.Name)
+	hash, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+	err = s.db.SetUserPassword(context.Background(), database.SetUserPasswordParams{
+		ID:           currentUser.ID,
+		PasswordHash: hash,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Password changed for %s\n", currentUser.Name)
+	return nil
+}
+
+func handlerToken(s *state, cmd command, currentUser database.User) error {
+	args := cmd.arguments[1:]
+	switch cmd.arguments[0] {
+	case "create":
+		if len(args) != 1 {
+			return newUsageError("token create: wrong number of arguments\n%s", tokenUsage)
+		}
+		return handlerTokenCreate(s, args[0], currentUser)
+	case "list":
+		if len(args) != 0 {
+			return newUsageError("token list: wrong number of arguments\n%s", tokenUsage)
+		}
+		return handlerTokenList(s, currentUser)
+	case "revoke":
+		if len(args) != 1 {
+			return newUsageError("token revoke: wrong number of arguments\n%s", tokenUsage)
+		}
+		return handlerTokenRevoke(s, args[0], currentUser)
+	default:
+		return newUsageError("token: unknown subcommand %s\n%s", cmd.arguments[0], tokenUsage)
+	}
+}
+
+// handlerTokenCreate asks for the user's password before minting a token,
+// since the token acts as the user anywhere gator serve is reachable.
+func handlerTokenCreate(s *state, name string, currentUser database.User) error {
+	if err := checkPassword(currentUser); err != nil {
+		return err
+	}
+	token, hash, prefix, err := auth.NewToken()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.CreateAPIToken(context.Background(), database.CreateAPITokenParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UserID:      currentUser.ID,
+		Name:        name,
+		TokenHash:   hash,
+		TokenPrefix: prefix,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Created token %q for %s. Copy it now, it won't be shown again:\n", name, currentUser.Name)
+	fmt.Println(token)
+	return nil
+}
+
+func handlerTokenList(s *state, currentUser database.User) error {
+	tokens, err := s.db.GetAPITokensForUser(context.Background(), currentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	if s.output != "" {
+		table := output.Table{Columns: []string{"id", "name", "prefix", "created_at", "last_used_at"}}
+		for _, token := range tokens {
+			table.Rows = append(table.Rows, []any{
+				token.ID, token.Name, token.TokenPrefix, token.CreatedAt, token.LastUsedAt,
+			})
+		}
+		return output.Write(os.Stdout, s.output, table)
+	}
+
+	for _, token := range tokens {
+		lastUsed := "never used"
+		if token.LastUsedAt.Valid {
+			lastUsed = "last used " + token.LastUsedAt.Time.Format(time.DateOnly)
+		}
+		fmt.Printf("%s (%s…) created %s, %s\n",
+			token.Name, token.TokenPrefix, token.CreatedAt.Format(time.DateOnly), lastUsed)
+	}
+	return nil
+}
+
+func handlerTokenRevoke(s *state, token string, currentUser database.User) error {
+	revoked, err := s.db.DeleteAPIToken(context.Background(), database.DeleteAPITokenParams{
+		UserID: currentUser.ID,
+		Token:  token,
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return fmt.Errorf("token not found: %s", token)
+	}
+	fmt.Printf("Token %s revoked\n", token)
+	return nil
+}
+
+// handlerFever sets or removes the password Fever clients log in with.
+// Fever keys are a fast hash of it, so it shouldn't be the gator password.
+func handlerFever(s *state, cmd command, currentUser database.User) error {
+	var keyHash sql.NullString
+	switch cmd.arguments[0] {
+	case "enable":
+		if err := checkPassword(currentUser); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Fever password for %s (don't reuse your gator password)\n", currentUser.Name)
+		password, err := askNewPassword()
+		if err != nil {
+			return err
+		}
+		key, err := auth.FeverKey(currentUser.Name, password)
+		if err != nil {
+			return err
+		}
+		keyHash = sql.NullString{String: auth.HashToken(key), Valid: true}
+	case "disable":
+	default:
+		return newUsageError("fever: unknown subcommand %s\n%s", cmd.arguments[0], feverUsage)
+	}
+	err := s.db.SetUserFeverKey(context.Background(), database.SetUserFeverKeyParams{
+		ID:           currentUser.ID,
+		FeverKeyHash: keyHash,
+	})
+	if err != nil {
+		return err
+	}
+	if keyHash.Valid {
+		fmt.Printf("Fever access enabled for %s, log in as %s with that password\n", currentUser.Name, currentUser.Name)
+	} else {
+		fmt.Printf("Fever access disabled for %s\n", currentUser.Name)
+	}
+	return nil
+}
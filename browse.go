@@ -0,0 +1,130 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/database"
+)
+
+// browse shows 2 posts unless told otherwise
+const defaultBrowseLimit = 2
+
+var browseSortOrders = []string{"published", "oldest", "fetched", "feed"}
+
+// addTimelineFlags adds the flags that pick posts from the user's timeline,
+// shared by browse and export.
+func addTimelineFlags(flags *flag.FlagSet, defaultLimit int) {
+	flags.Int("limit", defaultLimit, "number of posts to show")
+	flags.Int("offset", 0, "skip this many posts")
+	flags.Int("page", 0, "show this page of --limit posts, starting at 1")
+	flags.String("feed", "", "only show posts from the feed with this url or name")
+	flags.String("since", "", "only show posts published at or after this date, time or duration ago")
+	flags.String("until", "", "only show posts published before this date, time or duration ago")
+	flags.String("search", "", "only show posts whose title or description contains this text")
+	flags.String("sort", "published", "sort by published, oldest, fetched or feed")
+	flags.String("tag", "", "only show posts a rule tagged with this tag")
+	flags.Bool("hidden", false, "include posts hidden by rules")
+}
+
+// browseParams turns the flags added by addTimelineFlags into query
+// parameters for the user.
+func browseParams(cmd command, userID uuid.UUID, now time.Time) (database.GetPostsForUserParams, error) {
+	params := database.GetPostsForUserParams{
+		UserID: uuid.NullUUID{
+			UUID:  userID,
+			Valid: true,
+		},
+		SortBy:        cmd.stringFlag("sort"),
+		IncludeHidden: cmd.boolFlag("hidden"),
+	}
+
+	limit := cmd.intFlag("limit")
+	if len(cmd.arguments) >= 1 {
+		// the positional limit predates --limit and is kept for scripts
+		var err error
+		limit, err = strconv.Atoi(cmd.arguments[0])
+		if err != nil {
+			return params, newUsageError("%s: limit must be a positive number, got %q\nusage: gator browse [flags] [limit]", cmd.name, cmd.arguments[0])
+		}
+	}
+	if limit < 1 {
+		return params, newUsageError("%s: limit must be a positive number, got %d", cmd.name, limit)
+	}
+	if limit > math.MaxInt32 {
+		return params, newUsageError("%s: limit can be at most %d, got %d", cmd.name, math.MaxInt32, limit)
+	}
+	params.RowLimit = int32(limit)
+
+	offset, page := cmd.intFlag("offset"), cmd.intFlag("page")
+	switch {
+	case offset < 0:
+		return params, newUsageError("%s: --offset can't be negative", cmd.name)
+	case page < 0:
+		return params, newUsageError("%s: --page starts at 1", cmd.name)
+	case offset > 0 && page > 0:
+		return params, newUsageError("%s: use either --offset or --page, not both", cmd.name)
+	case page-1 > math.MaxInt32/limit:
+		return params, newUsageError("%s: --page %d is past the last possible post", cmd.name, page)
+	case page > 0:
+		offset = (page - 1) * limit
+	}
+	if offset > math.MaxInt32 {
+		return params, newUsageError("%s: --offset can be at most %d, got %d", cmd.name, math.MaxInt32, offset)
+	}
+	params.RowOffset = int32(offset)
+
+	validSort := false
+	for _, order := range browseSortOrders {
+		validSort = validSort || order == params.SortBy
+	}
+	if !validSort {
+		return params, newUsageError("%s: unknown sort order %q, expected published, oldest, fetched or feed", cmd.name, params.SortBy)
+	}
+
+	if feed := cmd.stringFlag("feed"); feed != "" {
+		params.Feed = sql.NullString{String: feed, Valid: true}
+	}
+	if search := cmd.stringFlag("search"); search != "" {
+		params.Search = sql.NullString{String: search, Valid: true}
+	}
+	if tag := cmd.stringFlag("tag"); tag != "" {
+		params.Tag = sql.NullString{String: tag, Valid: true}
+	}
+	for _, bound := range []struct {
+		flag string
+		dest *sql.NullTime
+	}{{"since", &params.Since}, {"until", &params.Until}} {
+		value := cmd.stringFlag(bound.flag)
+		if value == "" {
+			continue
+		}
+		t, err := parseTimeBound(value, now)
+		if err != nil {
+			return params, newUsageError("%s: invalid --%s %q: use a date, an RFC 3339 time or a duration such as 24h", cmd.name, bound.flag, value)
+		}
+		*bound.dest = sql.NullTime{Time: t, Valid: true}
+	}
+	return params, nil
+}
+
+// parseTimeBound accepts "2006-01-02", "2006-01-02 15:04", RFC 3339 times,
+// or a duration meaning that long ago.
+func parseTimeBound(value string, now time.Time) (time.Time, error) {
+	if ago, err := time.ParseDuration(value); err == nil {
+		return now.Add(-ago), nil
+	}
+	var lastErr error
+	for _, layout := range []string{time.DateOnly, "2006-01-02 15:04", time.RFC3339} {
+		t, err := time.ParseInLocation(layout, value, now.Location())
+		if err == nil {
+			return t, nil
+		}
+		lastErr = err
+	}
+	return time.Time{}, lastErr
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBrowseParams(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantLimit  int32
+		wantOffset int32
+		wantErr    bool
+	}{
+		{name: "defaults", wantLimit: 2},
+		{name: "positional limit", args: []string{"10"}, wantLimit: 10},
+		{name: "page", args: []string{"--limit", "10", "--page", "3"}, wantLimit: 10, wantOffset: 20},
+		{name: "offset", args: []string{"--offset", "7"}, wantLimit: 2, wantOffset: 7},
+		{name: "oldest first", args: []string{"--sort", "oldest"}, wantLimit: 2},
+		{name: "largest limit", args: []string{"--limit", "2147483647"}, wantLimit: 2147483647},
+		{name: "largest page", args: []string{"--limit", "1", "--page", "2147483648"}, wantLimit: 1, wantOffset: 2147483647},
+		{name: "limit too large", args: []string{"--limit", "2147483648"}, wantErr: true},
+		{name: "positional limit too large", args: []string{"4294967297"}, wantErr: true},
+		{name: "offset too large", args: []string{"--offset", "2147483648"}, wantErr: true},
+		{name: "page past the end", args: []string{"--limit", "1", "--page", "2147483649"}, wantErr: true},
+		{name: "page wrapping an int", args: []string{"--limit", "100", "--page", strconv.Itoa(1 << 60)}, wantErr: true},
+		{name: "zero limit", args: []string{"--limit", "0"}, wantErr: true},
+		{name: "unknown sort", args: []string{"--sort", "random"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := flag.NewFlagSet("browse", flag.ContinueOnError)
+			flags.SetOutput(io.Discard)
+			addTimelineFlags(flags, defaultBrowseLimit)
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			cmd := command{name: "browse", arguments: flags.Args(), flags: flags}
+
+			params, err := browseParams(cmd, uuid.New(), time.Now())
+			if tt.wantErr {
+				var usageErr usageError
+				if !errors.As(err, &usageErr) {
+					t.Fatalf("browseParams() error = %v, want a usage error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("browseParams() error = %v", err)
+			}
+			if params.RowLimit != tt.wantLimit || params.RowOffset != tt.wantOffset {
+				t.Errorf("limit, offset = %d, %d, want %d, %d", params.RowLimit, params.RowOffset, tt.wantLimit, tt.wantOffset)
+			}
+		})
+	}
+}
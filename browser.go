@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// openInBrowser runs the first command in $BROWSER that works, falling back
+// to the platform's opener. $BROWSER is a colon separated list of commands in
+// which %s stands for the URL; without it the URL is the last argument. The
+// browser gets the terminal and gator waits for it, so text browsers work.
+// Only http and https links are opened: post links come from feeds, and the
+// platform openers would as happily run file: or other protocol handlers.
+func openInBrowser(link string) error {
+	u, err := url.Parse(link)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("not an http or https link: %s", link)
+	}
+
+	var candidates [][]string
+	for _, browser := range strings.Split(os.Getenv("BROWSER"), ":") {
+		fields := strings.Fields(browser)
+		if len(fields) == 0 {
+			continue
+		}
+		hasURL := false
+		for i, field := range fields {
+			if strings.Contains(field, "%s") {
+				fields[i] = strings.ReplaceAll(field, "%s", link)
+				hasURL = true
+			}
+		}
+		if !hasURL {
+			fields = append(fields, link)
+		}
+		candidates = append(candidates, fields)
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = append(candidates, []string{"open", link})
+	case "windows":
+		candidates = append(candidates, []string{"rundll32", "url.dll,FileProtocolHandler", link})
+	default:
+		candidates = append(candidates, []string{"xdg-open", link})
+	}
+
+	var errs []error
+	for _, args := range candidates {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		err := cmd.Run()
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+// Only refusals are tested; an accepted link would start a browser.
+func TestOpenInBrowserRefusesOtherSchemes(t *testing.T) {
+	t.Setenv("BROWSER", "false")
+	for _, link := range []string{
+		"",
+		"file:///etc/passwd",
+		"FILE:///C:/Windows/System32/calc.exe",
+		"javascript:alert(1)",
+		"ssh://example.com",
+		"smb://example.com/share",
+		"mailto:someone@example.com",
+		"//example.com/post",
+		"/relative/post",
+		"http:///no-host",
+		"-flag",
+	} {
+		err := openInBrowser(link)
+		if err == nil || err.Error() != "not an http or https link: "+link {
+			t.Errorf("openInBrowser(%q) = %v, want it refused", link, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// byteOrderMarks are the marks that give away a document's encoding, which
+// encoding/xml can't read past on its own.
+var byteOrderMarks = []struct {
+	mark  string
+	label string
+}{
+	{"\xef\xbb\xbf", "utf-8"},
+	{"\xff\xfe", "utf-16le"},
+	{"\xfe\xff", "utf-16be"},
+}
+
+// decodeFeed unmarshals feed XML in whatever character set the publisher used.
+// A byte order mark wins over everything else, then a charset in the
+// Content-Type header as RFC 7303 asks; otherwise the XML declaration decides.
+func decodeFeed(data []byte, contentType string) (*RSSFeed, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charset.NewReaderLabel
+
+	label := headerCharset(contentType)
+	for _, bom := range byteOrderMarks {
+		if rest, ok := bytes.CutPrefix(data, []byte(bom.mark)); ok {
+			label, data = bom.label, rest
+			break
+		}
+	}
+	if label != "" {
+		if !isUTF8(label) {
+			enc, _ := charset.Lookup(label)
+			if enc == nil {
+				return nil, fmt.Errorf("unsupported charset %q", label)
+			}
+			var err error
+			data, err = enc.NewDecoder().Bytes(data)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding %s feed: %w", label, err)
+			}
+		}
+		decoder = xml.NewDecoder(bytes.NewReader(data))
+		// The bytes are UTF-8 by now, whatever the declaration still claims.
+		decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+	}
+
+	var rssFeed RSSFeed
+	if err := decoder.Decode(&rssFeed); err != nil {
+		return nil, err
+	}
+	return &rssFeed, nil
+}
+
+func headerCharset(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(params["charset"])
+}
+
+func isUTF8(label string) bool {
+	label = strings.ToLower(label)
+	return label == "utf-8" || label == "utf8"
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func rssWithTitle(declaration, title string) string {
+	return declaration + "<rss><channel><title>" + title + "</title></channel></rss>"
+}
+
+func utf16Bytes(s string, order binary.AppendByteOrder, bom bool) []byte {
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xfeff}, units...)
+	}
+	out := make([]byte, 0, 2*len(units))
+	for _, unit := range units {
+		out = order.AppendUint16(out, unit)
+	}
+	return out
+}
+
+func TestDecodeFeed(t *testing.T) {
+	const utf16Decl = `<?xml version="1.0" encoding="UTF-16"?>`
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		want        string
+		wantErr     string
+	}{
+		{
+			name: "plain utf-8",
+			data: []byte(rssWithTitle(`<?xml version="1.0"?>`, "Café")),
+			want: "Café",
+		},
+		{
+			name: "latin-1 from the declaration",
+			data: []byte(rssWithTitle(`<?xml version="1.0" encoding="ISO-8859-1"?>`, "Caf\xe9")),
+			want: "Café",
+		},
+		{
+			name: "windows-1252 from the declaration",
+			data: []byte(rssWithTitle(`<?xml version="1.0" encoding="windows-1252"?>`, "\x93quoted\x94")),
+			want: "“quoted”",
+		},
+		{
+			name:        "header wins over the declaration",
+			data:        []byte(rssWithTitle(`<?xml version="1.0" encoding="UTF-8"?>`, "Caf\xe9")),
+			contentType: "application/rss+xml; charset=iso-8859-1",
+			want:        "Café",
+		},
+		{
+			name:        "utf-8 header over a latin-1 declaration",
+			data:        []byte(rssWithTitle(`<?xml version="1.0" encoding="ISO-8859-1"?>`, "Café")),
+			contentType: "text/xml; charset=UTF-8",
+			want:        "Café",
+		},
+		{
+			name:        "header without a charset",
+			data:        []byte(rssWithTitle(`<?xml version="1.0" encoding="ISO-8859-1"?>`, "Caf\xe9")),
+			contentType: "application/rss+xml",
+			want:        "Café",
+		},
+		{
+			name:        "unknown header charset",
+			data:        []byte(rssWithTitle("", "Café")),
+			contentType: "text/xml; charset=x-made-up",
+			wantErr:     `unsupported charset "x-made-up"`,
+		},
+		{
+			name:    "unknown declared charset",
+			data:    []byte(rssWithTitle(`<?xml version="1.0" encoding="x-made-up"?>`, "Café")),
+			wantErr: "x-made-up",
+		},
+		{
+			name: "utf-8 byte order mark",
+			data: []byte("\xef\xbb\xbf" + rssWithTitle(`<?xml version="1.0"?>`, "Café")),
+			want: "Café",
+		},
+		{
+			name: "utf-16le byte order mark",
+			data: utf16Bytes(rssWithTitle(utf16Decl, "Café"), binary.LittleEndian, true),
+			want: "Café",
+		},
+		{
+			name: "utf-16be byte order mark",
+			data: utf16Bytes(rssWithTitle(utf16Decl, "Café"), binary.BigEndian, true),
+			want: "Café",
+		},
+		{
+			name:        "byte order mark wins over the header",
+			data:        utf16Bytes(rssWithTitle(utf16Decl, "Café"), binary.LittleEndian, true),
+			contentType: "application/xml; charset=iso-8859-1",
+			want:        "Café",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := decodeFeed(tt.data, tt.contentType)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decodeFeed() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeFeed() error = %v", err)
+			}
+			if feed.Channel.Title != tt.want {
+				t.Errorf("title = %q, want %q", feed.Channel.Title, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jamistoso/gator/internal/output"
+)
+
+// Exit codes: 0 on success, exitFailure when a command fails, exitUsage when
+// it was invoked incorrectly.
+const (
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+type command struct {
+	name      string
+	arguments []string
+	flags     *flag.FlagSet
+}
+
+// commandInfo describes how a command is invoked. It drives argument
+// validation, flag parsing and help output.
+type commandInfo struct {
+	// usage lists the positional arguments, e.g. "<url> [name]".
+	usage   string
+	summary string
+	minArgs int
+	// maxArgs of -1 allows any number of arguments.
+	maxArgs  int
+	setFlags func(*flag.FlagSet)
+	hidden   bool
+	// listing commands accept --output.
+	listing bool
+	// rawArgs hands the arguments over untouched, without flag parsing.
+	rawArgs bool
+	// subcommands and completeArgs feed shell completion.
+	subcommands  []string
+	completeArgs argCompletion
+}
+
+type commands struct {
+	funcMap map[string]func(*state, command) error
+	infoMap map[string]commandInfo
+}
+
+// usageError is returned when a command is invoked incorrectly.
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+func newUsageError(format string, args ...any) error {
+	return usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+func exitCode(err error) int {
+	var usageErr usageError
+	if errors.As(err, &usageErr) {
+		return exitUsage
+	}
+	return exitFailure
+}
+
+func (c *commands) register(name string, f func(*state, command) error, info commandInfo) {
+	c.funcMap[name] = f
+	c.infoMap[name] = info
+}
+
+func (c *commands) run(s *state, cmd command) error {
+	valCmd, ok := c.funcMap[cmd.name]
+	if !ok {
+		msg := fmt.Sprintf("command not found: %s", cmd.name)
+		if suggestions := c.suggest(cmd.name); len(suggestions) > 0 {
+			msg += fmt.Sprintf("\nDid you mean %s?", strings.Join(suggestions, " or "))
+		}
+		return newUsageError("%s\nRun 'gator help' for a list of commands.", msg)
+	}
+	info := c.infoMap[cmd.name]
+	if info.rawArgs {
+		return valCmd(s, cmd)
+	}
+
+	flags := c.flagSet(cmd.name)
+	positional, err := parseArguments(flags, cmd.arguments)
+	if errors.Is(err, flag.ErrHelp) {
+		c.printCommandHelp(os.Stdout, cmd.name)
+		return nil
+	}
+	if err != nil {
+		return newUsageError("%s: %v\nusage: %s", cmd.name, err, c.usageLine(cmd.name))
+	}
+	if len(positional) < info.minArgs || (info.maxArgs >= 0 && len(positional) > info.maxArgs) {
+		return newUsageError("%s: wrong number of arguments\nusage: %s", cmd.name, c.usageLine(cmd.name))
+	}
+
+	if format := flags.Lookup("output"); format != nil && format.Value.String() != "" {
+		s.output = format.Value.String()
+	}
+	if s.output != "" && !output.ValidFormat(s.output) {
+		return newUsageError("%s: unknown output format %q, expected one of %s", cmd.name, s.output, strings.Join(output.Formats, ", "))
+	}
+
+	cmd.arguments = positional
+	cmd.flags = flags
+	return valCmd(s, cmd)
+}
+
+func addOutputFlag(flags *flag.FlagSet) {
+	flags.String("output", "", "print records as "+strings.Join(output.Formats, ", ")+" instead of plain text")
+}
+
+func (c *commands) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	if c.infoMap[name].listing {
+		addOutputFlag(flags)
+	}
+	if setFlags := c.infoMap[name].setFlags; setFlags != nil {
+		setFlags(flags)
+	}
+	return flags
+}
+
+// parseArguments parses flags wherever they appear among the positional
+// arguments, so "gator fetch <url> --dry-run" works as well as the reverse.
+// Everything after a "--" is positional.
+func parseArguments(flags *flag.FlagSet, arguments []string) ([]string, error) {
+	var rest []string
+	for i, arg := range arguments {
+		if arg == "--" {
+			arguments, rest = arguments[:i], arguments[i+1:]
+			break
+		}
+	}
+
+	var positional []string
+	for {
+		if err := flags.Parse(arguments); err != nil {
+			return nil, err
+		}
+		arguments = flags.Args()
+		if len(arguments) == 0 {
+			break
+		}
+		positional = append(positional, arguments[0])
+		arguments = arguments[1:]
+	}
+	return append(positional, rest...), nil
+}
+
+func (c *commands) usageLine(name string) string {
+	line := "gator " + name
+	hasFlags := false
+	c.flagSet(name).VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		line += " [flags]"
+	}
+	if usage := c.infoMap[name].usage; usage != "" {
+		line += " " + usage
+	}
+	return line
+}
+
+func (c *commands) visibleNames() []string {
+	names := make([]string, 0, len(c.infoMap))
+	for name, info := range c.infoMap {
+		if !info.hidden {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *commands) printHelp(w io.Writer) {
+	fmt.Fprintln(w, "usage: gator <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, name := range c.visibleNames() {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, c.infoMap[name].summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'gator help <command>' or 'gator <command> --help' for details.")
+}
+
+func (c *commands) printCommandHelp(w io.Writer, name string) {
+	fmt.Fprintf(w, "usage: %s\n", c.usageLine(name))
+	if summary := c.infoMap[name].summary; summary != "" {
+		fmt.Fprintf(w, "\n%s\n", summary)
+	}
+
+	flags := c.flagSet(name)
+	hasFlags := false
+	flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintln(w, "\nFlags:")
+		flags.SetOutput(w)
+		flags.PrintDefaults()
+	}
+}
+
+func (c *commands) handlerHelp(s *state, cmd command) error {
+	if len(cmd.arguments) == 0 {
+		c.printHelp(os.Stdout)
+		return nil
+	}
+	name := cmd.arguments[0]
+	if _, ok := c.funcMap[name]; !ok {
+		return c.run(s, command{name: name})
+	}
+	c.printCommandHelp(os.Stdout, name)
+	return nil
+}
+
+// suggest returns registered command names close to a mistyped one.
+func (c *commands) suggest(name string) []string {
+	var suggestions []string
+	for _, candidate := range c.visibleNames() {
+		if strings.HasPrefix(candidate, name) || levenshtein(name, candidate) <= 2 {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return suggestions
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func (cmd command) boolFlag(name string) bool {
+	return cmd.flags.Lookup(name).Value.(flag.Getter).Get().(bool)
+}
+
+func (cmd command) stringFlag(name string) string {
+	return cmd.flags.Lookup(name).Value.String()
+}
+
+func (cmd command) intFlag(name string) int {
+	return cmd.flags.Lookup(name).Value.(flag.Getter).Get().(int)
+}
+
+func (cmd command) durationFlag(name string) time.Duration {
+	return cmd.flags.Lookup(name).Value.(flag.Getter).Get().(time.Duration)
+}
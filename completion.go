@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/output"
+)
+
+// argCompletion says what a command's positional arguments complete to.
+type argCompletion int
+
+const (
+	completeNone argCompletion = iota
+	completeCommands
+	completeUsers
+	completeFeeds
+	completeFollowedFeeds
+)
+
+type completion struct {
+	value       string
+	description string
+}
+
+const bashCompletion = `# bash completion for gator
+_gator_completions() {
+    local line="${COMP_LINE:0:COMP_POINT}"
+    local -a words
+    read -ra words <<< "$line"
+    [[ "$line" == *" " ]] && words+=("")
+    local cur="${words[-1]}"
+    mapfile -t COMPREPLY < <(gator __complete "${words[@]:1}" 2>/dev/null | cut -f1)
+    # bash splits words on ':', so only complete what follows the last one
+    if [[ "$cur" == *:* && "$COMP_WORDBREAKS" == *:* ]]; then
+        local prefix="${cur%"${cur##*:}"}"
+        COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+    fi
+}
+complete -F _gator_completions gator
+`
+
+const zshCompletion = `#compdef gator
+# zsh completion for gator
+_gator() {
+    local -a candidates
+    local line
+    for line in "${(@f)$(gator __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -z "$line" ]] && continue
+        candidates+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+    done
+    _describe 'gator' candidates
+}
+compdef _gator gator
+`
+
+const fishCompletion = `# fish completion for gator
+function __gator_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    gator __complete $tokens[2..-1] 2>/dev/null
+end
+complete -c gator -f -a '(__gator_complete)'
+`
+
+func handlerCompletion(s *state, cmd command) error {
+	switch cmd.arguments[0] {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "fish":
+		fmt.Print(fishCompletion)
+	default:
+		return newUsageError("completion: unsupported shell %s\nusage: gator completion bash|zsh|fish", cmd.arguments[0])
+	}
+	return nil
+}
+
+// handlerComplete is called by the completion scripts with the words typed
+// so far, the last one being the word under the cursor. It prints one
+// "candidate<TAB>description" line per match.
+func (c *commands) handlerComplete(s *state, cmd command) error {
+	words := cmd.arguments
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+
+	for _, candidate := range c.completions(s, words[:len(words)-1], current) {
+		if strings.HasPrefix(candidate.value, current) {
+			fmt.Printf("%s\t%s\n", candidate.value, candidate.description)
+		}
+	}
+	return nil
+}
+
+func (c *commands) completions(s *state, previous []string, current string) []completion {
+	// skip global flags in front of the command name
+	for len(previous) > 0 && strings.HasPrefix(previous[0], "-") {
+		if strings.Contains(previous[0], "=") || strings.TrimLeft(previous[0], "-") != "output" {
+			previous = previous[1:]
+			continue
+		}
+		if len(previous) == 1 {
+			return flagValueCompletions(s, &flag.Flag{Name: "output"})
+		}
+		previous = previous[2:]
+	}
+	if len(previous) == 0 {
+		return c.commandCompletions()
+	}
+	name := previous[0]
+	info, ok := c.infoMap[name]
+	if !ok {
+		return nil
+	}
+	flags := c.flagSet(name)
+
+	if strings.HasPrefix(current, "-") {
+		candidates := []completion{{value: "--help", description: "show help for " + name}}
+		flags.VisitAll(func(f *flag.Flag) {
+			candidates = append(candidates, completion{value: "--" + f.Name, description: f.Usage})
+		})
+		return candidates
+	}
+
+	// count the positional arguments before the cursor, skipping flags and
+	// the values of flags that take one
+	position := 0
+	for i := 1; i < len(previous); i++ {
+		word := previous[i]
+		if !strings.HasPrefix(word, "-") || word == "-" {
+			position++
+			continue
+		}
+		if f := flags.Lookup(strings.TrimLeft(word, "-")); f != nil && !isBoolFlag(f) {
+			if i == len(previous)-1 {
+				return flagValueCompletions(s, f)
+			}
+			i++
+		}
+	}
+
+	if position == 0 && len(info.subcommands) > 0 {
+		candidates := make([]completion, 0, len(info.subcommands))
+		for _, subcommand := range info.subcommands {
+			candidates = append(candidates, completion{value: subcommand})
+		}
+		return candidates
+	}
+
+	switch info.completeArgs {
+	case completeCommands:
+		return c.commandCompletions()
+	case completeUsers:
+		return userCompletions(s)
+	case completeFeeds:
+		return feedCompletions(s)
+	case completeFollowedFeeds:
+		return followedFeedCompletions(s)
+	}
+	return nil
+}
+
+func (c *commands) commandCompletions() []completion {
+	names := c.visibleNames()
+	candidates := make([]completion, 0, len(names))
+	for _, name := range names {
+		candidates = append(candidates, completion{value: name, description: c.infoMap[name].summary})
+	}
+	return candidates
+}
+
+func flagValueCompletions(s *state, f *flag.Flag) []completion {
+	var values []string
+	switch f.Name {
+	case "output":
+		values = output.Formats
+	case "sort":
+		values = browseSortOrders
+	case "color":
+		values = []string{"auto", "always", "never"}
+	case "feed":
+		return followedFeedCompletions(s)
+	}
+	candidates := make([]completion, 0, len(values))
+	for _, value := range values {
+		candidates = append(candidates, completion{value: value})
+	}
+	return candidates
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+// The database backed completions stay quiet on errors: a broken
+// connection should not spill into the user's shell.
+
+func userCompletions(s *state) []completion {
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		return nil
+	}
+	candidates := make([]completion, 0, len(users))
+	for _, user := range users {
+		candidates = append(candidates, completion{value: user.Name})
+	}
+	return candidates
+}
+
+func feedCompletions(s *state) []completion {
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return nil
+	}
+	candidates := make([]completion, 0, len(feeds))
+	for _, feed := range feeds {
+		candidates = append(candidates, completion{value: feed.Url, description: feed.Name})
+	}
+	return candidates
+}
+
+func followedFeedCompletions(s *state) []completion {
+	user, err := s.db.GetUser(context.Background(), s.cfg.Current_user_name)
+	if err != nil {
+		return nil
+	}
+	feeds, err := s.db.GetFollowedFeeds(context.Background(), uuid.NullUUID{UUID: user.ID, Valid: true})
+	if err != nil {
+		return nil
+	}
+	candidates := make([]completion, 0, len(feeds))
+	for _, feed := range feeds {
+		candidates = append(candidates, completion{value: feed.Url, description: feed.Name})
+	}
+	return candidates
+}
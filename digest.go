@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/jamistoso/gator/internal/digest"
+	"github.com/jamistoso/gator/internal/htmltext"
+)
+
+const (
+	digestUsage = "usage: gator digest set <email> | show | off | preview | send"
+	// digestMaxPosts is how many posts one digest lists.
+	digestMaxPosts = 100
+	// digestRetryDelay is how long a digest that couldn't be sent waits
+	// before the next try.
+	digestRetryDelay = 15 * time.Minute
+)
+
+func handlerDigest(s *state, cmd command, currentUser database.User) error {
+	args := cmd.arguments[1:]
+	switch cmd.arguments[0] {
+	case "set":
+		if len(args) != 1 {
+			return newUsageError("digest set: wrong number of arguments\n%s", digestUsage)
+		}
+		return handlerDigestSet(s, cmd, args[0], currentUser)
+	case "show", "off", "preview", "send":
+		if len(args) != 0 {
+			return newUsageError("digest %s: wrong number of arguments\n%s", cmd.arguments[0], digestUsage)
+		}
+	default:
+		return newUsageError("digest: unknown subcommand %s\n%s", cmd.arguments[0], digestUsage)
+	}
+	switch cmd.arguments[0] {
+	case "show":
+		return handlerDigestShow(s, currentUser)
+	case "off":
+		return handlerDigestOff(s, currentUser)
+	case "preview":
+		return handlerDigestPreview(s, cmd, currentUser)
+	default:
+		return handlerDigestSend(s, currentUser)
+	}
+}
+
+// handlerDigestSet schedules the user's digest, replacing any earlier
+// schedule.
+func handlerDigestSet(s *state, cmd command, email string, currentUser database.User) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil {
+		return fmt.Errorf("invalid email address %q: %w", email, err)
+	}
+	frequency := cmd.stringFlag("every")
+	if !digest.ValidFrequency(frequency) {
+		return newUsageError("digest set: unknown frequency %q, expected one of %s", frequency, strings.Join(digest.Frequencies, ", "))
+	}
+	at, err := time.Parse("15:04", cmd.stringFlag("at"))
+	if err != nil {
+		return newUsageError("digest set: --at must be a time such as 07:30, got %q", cmd.stringFlag("at"))
+	}
+	weekday, err := parseWeekday(cmd.stringFlag("day"))
+	if err != nil {
+		return newUsageError("digest set: %v", err)
+	}
+	loc, err := time.LoadLocation(cmd.stringFlag("tz"))
+	if err != nil {
+		return newUsageError("digest set: unknown time zone %q, expected a name such as Europe/Berlin", cmd.stringFlag("tz"))
+	}
+
+	// the digests table keeps its timestamps in UTC, since they're read back
+	// as UTC from columns without a zone
+	now := time.Now().UTC()
+	minute := at.Hour()*60 + at.Minute()
+	next := digest.Next(now, frequency, loc, minute, weekday)
+	_, err = s.db.UpsertDigest(context.Background(), database.UpsertDigestParams{
+		UserID:     currentUser.ID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Email:      address.Address,
+		Frequency:  frequency,
+		TimeZone:   loc.String(),
+		SendMinute: int32(minute),
+		Weekday:    int32(weekday),
+		NextSendAt: next.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Sending a %s digest to %s, the next one on %s\n", frequency, address.Address, next.In(loc).Format("Mon 2 Jan 15:04 MST"))
+	return nil
+}
+
+func handlerDigestShow(s *state, currentUser database.User) error {
+	d, err := s.db.GetDigest(context.Background(), currentUser.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Println("No digest is scheduled, set one up with 'gator digest set <email>'")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(d.TimeZone)
+	if err != nil {
+		return err
+	}
+	when := fmt.Sprintf("%02d:%02d", d.SendMinute/60, d.SendMinute%60)
+	if d.Frequency == digest.Weekly {
+		when = time.Weekday(d.Weekday).String() + "s at " + when
+	} else {
+		when = "at " + when
+	}
+	fmt.Printf("%s digest to %s, %s %s\n", capitalize(d.Frequency), d.Email, when, d.TimeZone)
+	if d.LastSentAt.Valid {
+		fmt.Printf("Last sent %s\n", d.LastSentAt.Time.In(loc).Format("Mon 2 Jan 15:04 MST"))
+	}
+	fmt.Printf("Next due %s\n", d.NextSendAt.In(loc).Format("Mon 2 Jan 15:04 MST"))
+	return nil
+}
+
+func handlerDigestOff(s *state, currentUser database.User) error {
+	removed, err := s.db.DeleteDigest(context.Background(), currentUser.ID)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		fmt.Println("No digest was scheduled")
+		return nil
+	}
+	fmt.Printf("Digest for %s turned off\n", currentUser.Name)
+	return nil
+}
+
+// handlerDigestPreview prints the digest that would be sent now, without
+// sending it. Users without a schedule get a daily one in UTC.
+func handlerDigestPreview(s *state, cmd command, currentUser database.User) error {
+	format := cmd.stringFlag("format")
+	if format != "text" && format != "html" {
+		return newUsageError("digest preview: unknown format %q, expected text or html", format)
+	}
+	d, err := s.db.GetDigest(context.Background(), currentUser.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		d = database.Digest{Frequency: digest.Daily, TimeZone: "UTC"}
+	} else if err != nil {
+		return err
+	}
+	rendered, err := buildDigest(context.Background(), s, currentUser.ID, currentUser.Name, d.Frequency, d.TimeZone, d.LastSentAt, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	var body string
+	if format == "html" {
+		body, err = rendered.HTML()
+	} else {
+		body, err = rendered.Text()
+	}
+	if err != nil {
+		return err
+	}
+	if format == "text" {
+		fmt.Printf("Subject: %s\n\n", rendered.Subject())
+	}
+	fmt.Print(body)
+	return nil
+}
+
+// handlerDigestSend sends the user's digest right away and starts the next
+// period from now.
+func handlerDigestSend(s *state, currentUser database.User) error {
+	d, err := s.db.GetDigest(context.Background(), currentUser.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New("no digest is scheduled, set one up with 'gator digest set <email>'")
+	}
+	if err != nil {
+		return err
+	}
+	return sendDigest(context.Background(), s, d, currentUser.Name, time.Now().UTC())
+}
+
+// sendDueDigests sends every digest whose time has come. Ones that fail are
+// tried again after digestRetryDelay.
+func sendDueDigests(ctx context.Context, s *state) error {
+	now := time.Now().UTC()
+	due, err := s.db.GetDueDigests(ctx, now)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, row := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		d := database.Digest{
+			UserID:     row.UserID,
+			Email:      row.Email,
+			Frequency:  row.Frequency,
+			TimeZone:   row.TimeZone,
+			SendMinute: row.SendMinute,
+			Weekday:    row.Weekday,
+			LastSentAt: row.LastSentAt,
+		}
+		err := sendDigest(ctx, s, d, row.UserName, now)
+		if err == nil {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("error sending %s's digest: %w", row.UserName, err))
+		err = s.db.MarkDigestSent(context.WithoutCancel(ctx), database.MarkDigestSentParams{
+			UserID:     row.UserID,
+			LastSentAt: row.LastSentAt,
+			NextSendAt: now.Add(digestRetryDelay),
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendDigest mails the unread posts since the last digest and schedules the
+// next one.
+func sendDigest(ctx context.Context, s *state, d database.Digest, userName string, now time.Time) error {
+	smtp := digest.SMTP{
+		Addr:     s.cfg.Smtp_addr,
+		Username: s.cfg.Smtp_username,
+		Password: s.cfg.SMTPPassword(),
+		From:     s.cfg.Smtp_from,
+	}
+	if smtp.Addr == "" || smtp.From == "" {
+		return errors.New("no SMTP server configured, set Smtp_addr and Smtp_from in ~/.gatorconfig.json")
+	}
+	loc, err := time.LoadLocation(d.TimeZone)
+	if err != nil {
+		return err
+	}
+	rendered, err := buildDigest(ctx, s, d.UserID, userName, d.Frequency, d.TimeZone, d.LastSentAt, now)
+	if err != nil {
+		return err
+	}
+	msg, err := digest.Message(rendered, smtp.From, d.Email, now.In(loc))
+	if err != nil {
+		return err
+	}
+	if err := smtp.Send(d.Email, msg); err != nil {
+		return err
+	}
+	err = s.db.MarkDigestSent(context.WithoutCancel(ctx), database.MarkDigestSentParams{
+		UserID:     d.UserID,
+		LastSentAt: sql.NullTime{Time: now, Valid: true},
+		NextSendAt: digest.Next(now, d.Frequency, loc, int(d.SendMinute), time.Weekday(d.Weekday)).UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Sent %s's %s digest to %s (%d posts)\n", userName, d.Frequency, d.Email, rendered.Count())
+	return nil
+}
+
+// buildDigest collects the unread posts fetched since the last digest, or
+// over the last period if there was none.
+func buildDigest(ctx context.Context, s *state, userID uuid.UUID, userName, frequency, timeZone string, lastSent sql.NullTime, now time.Time) (digest.Digest, error) {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return digest.Digest{}, err
+	}
+	since := now.Add(-digest.Period(frequency))
+	if lastSent.Valid {
+		since = lastSent.Time
+	}
+	posts, err := s.db.GetUnreadPostsSince(ctx, database.GetUnreadPostsSinceParams{
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+		// posts.created_at is in local time, unlike the digests table
+		Since:    since.In(time.Local),
+		RowLimit: digestMaxPosts + 1,
+	})
+	if err != nil {
+		return digest.Digest{}, err
+	}
+
+	rendered := digest.Digest{User: userName, Frequency: frequency, Since: since.In(loc)}
+	if len(posts) > digestMaxPosts {
+		posts = posts[:digestMaxPosts]
+		rendered.More = true
+	}
+	for _, post := range posts {
+		item := digest.Post{
+			Title: htmltext.Line(post.Title.String),
+			URL:   post.Url,
+			Text:  post.ContentText.String,
+		}
+		if post.PublishedAt.Valid {
+			item.Published = post.PublishedAt.Time.In(loc)
+		}
+		rendered.Add(post.FeedName, post.FeedUrl, item)
+	}
+	return rendered, nil
+}
+
+// parseWeekday accepts day names in any case, whole or cut to three
+// letters.
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(name)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown day %q, expected a weekday such as monday", name)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jamistoso/gator/internal/auth"
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/jamistoso/gator/internal/server"
+	"github.com/jamistoso/gator/internal/syndication"
+)
+
+const (
+	exportUsage = "usage: gator export feed | url [flags]"
+	// defaultExportLimit matches what serve puts in a timeline feed.
+	defaultExportLimit = 50
+)
+
+func handlerExport(s *state, cmd command, currentUser database.User) error {
+	format := cmd.stringFlag("format")
+	if !syndication.ValidFormat(format) {
+		return newUsageError("export: unknown format %q, expected one of %s", format, strings.Join(syndication.Formats, ", "))
+	}
+	switch cmd.arguments[0] {
+	case "feed":
+		return handlerExportFeed(s, cmd, format, currentUser)
+	case "url":
+		return handlerExportURL(s, cmd, format, currentUser)
+	default:
+		return newUsageError("export: unknown subcommand %s\n%s", cmd.arguments[0], exportUsage)
+	}
+}
+
+// handlerExportFeed writes the timeline picked by the browse flags to
+// stdout, as serve would.
+func handlerExportFeed(s *state, cmd command, format string, currentUser database.User) error {
+	// the subcommand isn't a limit
+	cmd.arguments = nil
+	params, err := browseParams(cmd, currentUser.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	posts, err := s.db.GetPostsForUser(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	return server.WriteTimeline(os.Stdout, format, currentUser, posts, cmd.stringFlag("url"))
+}
+
+// handlerExportURL prints the secret URL serve publishes the user's
+// timeline at, making up the secret the first time or when rotating it.
+func handlerExportURL(s *state, cmd command, format string, currentUser database.User) error {
+	secret := currentUser.FeedSecret.String
+	if !currentUser.FeedSecret.Valid || cmd.boolFlag("rotate") {
+		var err error
+		secret, err = auth.NewFeedSecret()
+		if err != nil {
+			return err
+		}
+		err = s.db.SetUserFeedSecret(context.Background(), database.SetUserFeedSecretParams{
+			ID:         currentUser.ID,
+			FeedSecret: sql.NullString{String: secret, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if currentUser.FeedSecret.Valid {
+			fmt.Fprintln(os.Stderr, "The previous URL no longer works.")
+		}
+	}
+	fmt.Println(strings.TrimSuffix(cmd.stringFlag("base"), "/") + server.TimelinePath(secret, format))
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nextFetchTime works out when a feed may be fetched again, honouring the
+// publisher's hints: RSS <ttl>, sy:updatePeriod/updateFrequency, the
+// Cache-Control and Retry-After headers, and <skipHours>/<skipDays>.
+// The longest requested delay wins, and it is never shorter than minDelay.
+// Hints can't push the fetch further out than maxDelay, unless minDelay is
+// longer still.
+func nextFetchTime(feed *RSSFeed, header http.Header, now time.Time, minDelay, maxDelay time.Duration) time.Time {
+	delay := minDelay
+	if feed != nil {
+		delay = max(delay, ttlDelay(feed.Channel.TTL))
+		delay = max(delay, syndicationDelay(feed.Channel.UpdatePeriod, feed.Channel.UpdateFrequency))
+	}
+	if header != nil {
+		delay = max(delay, maxAgeDelay(header.Get("Cache-Control")))
+		delay = max(delay, retryAfterDelay(header.Get("Retry-After"), now))
+	}
+
+	latest := now.Add(max(minDelay, maxDelay))
+	next := now.Add(delay)
+	if feed != nil {
+		next = skipAhead(next, feed.Channel.SkipHours, feed.Channel.SkipDays)
+	}
+	if next.After(latest) {
+		return latest
+	}
+	return next
+}
+
+func ttlDelay(ttl string) time.Duration {
+	minutes, err := strconv.Atoi(strings.TrimSpace(ttl))
+	if err != nil || minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func syndicationDelay(period, frequency string) time.Duration {
+	var base time.Duration
+	switch strings.ToLower(strings.TrimSpace(period)) {
+	case "hourly":
+		base = time.Hour
+	case "daily":
+		base = 24 * time.Hour
+	case "weekly":
+		base = 7 * 24 * time.Hour
+	case "monthly":
+		base = 30 * 24 * time.Hour
+	case "yearly":
+		base = 365 * 24 * time.Hour
+	default:
+		return 0
+	}
+	// updateFrequency defaults to once per period
+	times, err := strconv.Atoi(strings.TrimSpace(frequency))
+	if err != nil || times <= 0 {
+		times = 1
+	}
+	return base / time.Duration(times)
+}
+
+func maxAgeDelay(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(directive), "=")
+		if !found || !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+		if err != nil || seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	return 0
+}
+
+func retryAfterDelay(retryAfter string, now time.Time) time.Duration {
+	retryAfter = strings.TrimSpace(retryAfter)
+	if retryAfter == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(retryAfter); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// skipAhead moves t forward to the first hour that is not listed in
+// <skipHours> or <skipDays>. Both are expressed in GMT.
+func skipAhead(t time.Time, skipHours []int, skipDays []string) time.Time {
+	if len(skipHours) == 0 && len(skipDays) == 0 {
+		return t
+	}
+	hours := map[int]bool{}
+	for _, hour := range skipHours {
+		hours[hour%24] = true
+	}
+	days := map[time.Weekday]bool{}
+	for _, day := range skipDays {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if strings.EqualFold(strings.TrimSpace(day), weekday.String()) {
+				days[weekday] = true
+			}
+		}
+	}
+
+	// a week of hours covers every combination; give up if everything is skipped
+	candidate := t
+	for i := 0; i < 7*24; i++ {
+		utc := candidate.UTC()
+		if !hours[utc.Hour()] && !days[utc.Weekday()] {
+			return candidate
+		}
+		candidate = utc.Truncate(time.Hour).Add(time.Hour)
+	}
+	return t
+}
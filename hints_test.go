@@ -0,0 +1,224 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// hintsNow is a Wednesday.
+var hintsNow = time.Date(2024, time.March, 6, 10, 30, 0, 0, time.UTC)
+
+func TestTTLDelay(t *testing.T) {
+	tests := []struct {
+		ttl  string
+		want time.Duration
+	}{
+		{"60", time.Hour},
+		{" 15 ", 15 * time.Minute},
+		{"", 0},
+		{"0", 0},
+		{"-5", 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := ttlDelay(tt.ttl); got != tt.want {
+			t.Errorf("ttlDelay(%q) = %s, want %s", tt.ttl, got, tt.want)
+		}
+	}
+}
+
+func TestSyndicationDelay(t *testing.T) {
+	tests := []struct {
+		period, frequency string
+		want              time.Duration
+	}{
+		{"hourly", "", time.Hour},
+		{"hourly", "2", 30 * time.Minute},
+		{"Daily", "1", 24 * time.Hour},
+		{"daily", "4", 6 * time.Hour},
+		{"weekly", "", 7 * 24 * time.Hour},
+		{"monthly", "", 30 * 24 * time.Hour},
+		{"yearly", "", 365 * 24 * time.Hour},
+		{"daily", "0", 24 * time.Hour},
+		{"daily", "lots", 24 * time.Hour},
+		{"fortnightly", "1", 0},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		if got := syndicationDelay(tt.period, tt.frequency); got != tt.want {
+			t.Errorf("syndicationDelay(%q, %q) = %s, want %s", tt.period, tt.frequency, got, tt.want)
+		}
+	}
+}
+
+func TestMaxAgeDelay(t *testing.T) {
+	tests := []struct {
+		cacheControl string
+		want         time.Duration
+	}{
+		{"max-age=300", 5 * time.Minute},
+		{"public, max-age=3600", time.Hour},
+		{`Max-Age="120"`, 2 * time.Minute},
+		{"no-cache", 0},
+		{"max-age=0", 0},
+		{"max-age=abc", 0},
+		{"s-maxage=600", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := maxAgeDelay(tt.cacheControl); got != tt.want {
+			t.Errorf("maxAgeDelay(%q) = %s, want %s", tt.cacheControl, got, tt.want)
+		}
+	}
+}
+
+func TestRetryAfterDelay(t *testing.T) {
+	tests := []struct {
+		retryAfter string
+		want       time.Duration
+	}{
+		{"120", 2 * time.Minute},
+		{" 30 ", 30 * time.Second},
+		{"0", 0},
+		{"-10", 0},
+		{hintsNow.Add(90 * time.Minute).Format(http.TimeFormat), 90 * time.Minute},
+		{hintsNow.Add(-time.Hour).Format(http.TimeFormat), 0},
+		{"tomorrow", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfterDelay(tt.retryAfter, hintsNow); got != tt.want {
+			t.Errorf("retryAfterDelay(%q) = %s, want %s", tt.retryAfter, got, tt.want)
+		}
+	}
+}
+
+func TestSkipAhead(t *testing.T) {
+	tests := []struct {
+		name  string
+		t     time.Time
+		hours []int
+		days  []string
+		want  time.Time
+	}{
+		{
+			name: "nothing skipped",
+			t:    hintsNow,
+			want: hintsNow,
+		},
+		{
+			name:  "hour not skipped",
+			t:     hintsNow,
+			hours: []int{3, 4},
+			want:  hintsNow,
+		},
+		{
+			name:  "skipped hours",
+			t:     hintsNow,
+			hours: []int{10, 11, 12},
+			want:  time.Date(2024, time.March, 6, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "skipped hours past midnight",
+			t:     time.Date(2024, time.March, 6, 23, 15, 0, 0, time.UTC),
+			hours: []int{23, 24, 1},
+			want:  time.Date(2024, time.March, 7, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "skipped days",
+			t:    hintsNow,
+			days: []string{"Wednesday", " thursday "},
+			want: time.Date(2024, time.March, 8, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "hours in GMT",
+			t:     time.Date(2024, time.March, 6, 11, 30, 0, 0, time.FixedZone("CET", 3600)),
+			hours: []int{10},
+			want:  time.Date(2024, time.March, 6, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "everything skipped",
+			t:     hintsNow,
+			hours: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23},
+			want:  hintsNow,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := skipAhead(tt.t, tt.hours, tt.days); !got.Equal(tt.want) {
+				t.Errorf("skipAhead() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextFetchTime(t *testing.T) {
+	feedWith := func(set func(feed *RSSFeed)) *RSSFeed {
+		feed := &RSSFeed{}
+		set(feed)
+		return feed
+	}
+	const (
+		minDelay = 30 * time.Minute
+		maxDelay = 24 * time.Hour
+	)
+	tests := []struct {
+		name     string
+		feed     *RSSFeed
+		header   http.Header
+		minDelay time.Duration
+		want     time.Duration
+	}{
+		{
+			name: "no hints",
+			want: minDelay,
+		},
+		{
+			name: "hint shorter than the interval",
+			feed: feedWith(func(feed *RSSFeed) { feed.Channel.TTL = "5" }),
+			want: minDelay,
+		},
+		{
+			name:   "longest hint wins",
+			feed:   feedWith(func(feed *RSSFeed) { feed.Channel.TTL = "60" }),
+			header: http.Header{"Cache-Control": {"max-age=7200"}},
+			want:   2 * time.Hour,
+		},
+		{
+			name: "yearly update period capped",
+			feed: feedWith(func(feed *RSSFeed) { feed.Channel.UpdatePeriod = "yearly" }),
+			want: maxDelay,
+		},
+		{
+			name:   "large Retry-After capped",
+			header: http.Header{"Retry-After": {"31536000"}},
+			want:   maxDelay,
+		},
+		{
+			name: "skip days capped",
+			feed: feedWith(func(feed *RSSFeed) {
+				feed.Channel.SkipDays = []string{"Wednesday", "Thursday", "Friday"}
+			}),
+			want: maxDelay,
+		},
+		{
+			name:     "interval longer than the maximum kept",
+			header:   http.Header{"Retry-After": {"31536000"}},
+			minDelay: 48 * time.Hour,
+			want:     48 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval := tt.minDelay
+			if interval == 0 {
+				interval = minDelay
+			}
+			got := nextFetchTime(tt.feed, tt.header, hintsNow, interval, maxDelay).Sub(hintsNow)
+			if got != tt.want {
+				t.Errorf("delay = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
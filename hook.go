@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/jamistoso/gator/internal/hooks"
+	"github.com/jamistoso/gator/internal/htmltext"
+)
+
+// runHooks runs the configured hooks for each of a feed's new posts and
+// waits for them all, returning why any failed. Cancelling ctx kills the
+// hooks still running.
+func runHooks(ctx context.Context, s *state, dbFeed database.Feed, posts []database.UpsertPostRow) error {
+	if len(posts) == 0 || len(s.cfg.Hooks) == 0 {
+		return nil
+	}
+	timeout, concurrency, err := s.cfg.HookLimits()
+	if err != nil {
+		return err
+	}
+	runner := hooks.NewRunner(timeout, concurrency)
+	for _, hook := range s.cfg.Hooks {
+		if hook.Command == "" || (hook.Feed != "" && hook.Feed != dbFeed.Url) {
+			continue
+		}
+		for _, post := range posts {
+			runner.Start(ctx, hook.Command, hookPost(dbFeed, post))
+		}
+	}
+	return errors.Join(runner.Wait()...)
+}
+
+func hookPost(dbFeed database.Feed, post database.UpsertPostRow) hooks.Post {
+	out := hooks.Post{
+		ID:          post.ID.String(),
+		Title:       htmltext.Line(post.Title.String),
+		URL:         post.Url,
+		Author:      post.Author.String,
+		Categories:  post.Categories,
+		ContentHTML: post.ContentHtml.String,
+		ContentText: post.ContentText.String,
+		FeedName:    dbFeed.Name,
+		FeedURL:     dbFeed.Url,
+	}
+	if post.PublishedAt.Valid {
+		published := post.PublishedAt.Time
+		out.Published = &published
+	}
+	return out
+}
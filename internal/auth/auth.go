@@ -0,0 +1,90 @@
+// Package auth hashes and checks user passwords and API tokens, and makes
+// the secrets in timeline feed URLs and Fever API keys.
+package auth
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MinPasswordLength is the shortest password accepted.
+	MinPasswordLength = 8
+
+	// tokenPrefix marks gator tokens so they are easy to spot, e.g. by
+	// secret scanners.
+	tokenPrefix = "gator_"
+	tokenBytes  = 32
+	// shownPrefixLength is how much of a token is kept to recognise it by.
+	shownPrefixLength = len(tokenPrefix) + 6
+)
+
+var ErrWrongPassword = errors.New("wrong password")
+
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword returns ErrWrongPassword unless password matches hash.
+func CheckPassword(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrWrongPassword
+	}
+	return err
+}
+
+// NewToken makes a random API token. Only its hash should be stored, along
+// with the prefix used to tell tokens apart when listing them.
+func NewToken() (token, hash, prefix string, err error) {
+	random := make([]byte, tokenBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", "", err
+	}
+	token = tokenPrefix + base64.RawURLEncoding.EncodeToString(random)
+	return token, HashToken(token), token[:shownPrefixLength], nil
+}
+
+// HashToken is the form tokens are stored and looked up in. Tokens are long
+// and random, so a fast unsalted hash is enough.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewFeedSecret makes the random part of a timeline feed URL. Unlike tokens
+// it is stored as is, since feed readers can't send headers and the URL has
+// to be shown again.
+func NewFeedSecret() (string, error) {
+	random := make([]byte, tokenBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// FeverKey is the API key Fever clients derive from a user's name and
+// password. Like a token, only its HashToken should be stored. Being a fast
+// hash, it shouldn't be made from the user's gator password.
+func FeverKey(name, password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	sum := md5.Sum([]byte(name + ":" + password))
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -2,15 +2,47 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
+	"time"
 )
 
 const configFileName = ".gatorconfig.json"
 
+const defaultMinPollInterval = 10 * time.Minute
+const defaultMaxPollInterval = 24 * time.Hour
+
+// smtpPasswordEnv overrides Smtp_password, so it needn't be in the file.
+const smtpPasswordEnv = "GATOR_SMTP_PASSWORD"
+
+// configFileMode keeps the file, which can hold the SMTP password, private.
+const configFileMode = 0600
+
+const defaultHookTimeout = 30 * time.Second
+const defaultHookConcurrency = 4
+
 type Config struct{
 	Db_url				string
 	Current_user_name	string
+	Min_poll_interval	string
+	Max_poll_interval	string
+	// digests are sent through this SMTP server
+	Smtp_addr			string
+	Smtp_username		string
+	Smtp_password		string
+	Smtp_from			string
+	// commands run for each new post
+	Hooks				[]Hook
+	Hook_timeout		string
+	Hook_concurrency	int
+}
+
+// Hook is a command run through the shell for each new post.
+type Hook struct{
+	Command	string
+	// Feed limits the hook to posts from the feed with this URL.
+	Feed	string
 }
 
 func Read() Config{
@@ -32,6 +64,12 @@ func Read() Config{
         log.Fatalf("error unmarshalling JSON: %v", err)
 		return Config{}
     }
+	if config.Smtp_password != "" {
+		// files from before the password was kept here were world-readable
+		if err := os.Chmod(path, configFileMode); err != nil {
+			log.Printf("warning: %s holds Smtp_password but can't be made private: %v", path, err)
+		}
+	}
 	return config
 }
 
@@ -43,6 +81,59 @@ func (c Config) SetUser(user string) error {
 	return nil
 }
 
+// SMTPPassword returns the password for the SMTP server, taken from the
+// GATOR_SMTP_PASSWORD environment variable if it is set.
+func (c Config) SMTPPassword() string {
+	if password, ok := os.LookupEnv(smtpPasswordEnv); ok {
+		return password
+	}
+	return c.Smtp_password
+}
+
+// PollBounds returns the range adaptive polling intervals are kept within.
+func (c Config) PollBounds() (time.Duration, time.Duration, error) {
+	minInterval, maxInterval := defaultMinPollInterval, defaultMaxPollInterval
+	var err error
+	if c.Min_poll_interval != "" {
+		minInterval, err = time.ParseDuration(c.Min_poll_interval)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid Min_poll_interval: %w", err)
+		}
+	}
+	if c.Max_poll_interval != "" {
+		maxInterval, err = time.ParseDuration(c.Max_poll_interval)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid Max_poll_interval: %w", err)
+		}
+	}
+	if minInterval > maxInterval {
+		return 0, 0, fmt.Errorf("Min_poll_interval %s is longer than Max_poll_interval %s", minInterval, maxInterval)
+	}
+	return minInterval, maxInterval, nil
+}
+
+// HookLimits returns how long a hook may run and how many run at once.
+func (c Config) HookLimits() (time.Duration, int, error) {
+	timeout, concurrency := defaultHookTimeout, defaultHookConcurrency
+	if c.Hook_timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(c.Hook_timeout)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid Hook_timeout: %w", err)
+		}
+		if timeout <= 0 {
+			return 0, 0, fmt.Errorf("Hook_timeout must be positive, got %s", timeout)
+		}
+	}
+	if c.Hook_concurrency < 0 {
+		return 0, 0, fmt.Errorf("Hook_concurrency can't be negative, got %d", c.Hook_concurrency)
+	}
+	if c.Hook_concurrency > 0 {
+		concurrency = c.Hook_concurrency
+	}
+	return timeout, concurrency, nil
+}
+
 func getConfigFilePath() (string, error) {
 	path, err := os.UserHomeDir()
 	if err != nil {
@@ -66,9 +157,13 @@ func write(cfg Config) error {
 		return err
     }
 
-	if err := os.WriteFile(path, jsonData, 0666); err != nil {
+	if err := os.WriteFile(path, jsonData, configFileMode); err != nil {
         log.Fatalf("error writing to file %s: %v", path, err)
 	}
+	// WriteFile keeps the mode of a file that already exists
+	if err := os.Chmod(path, configFileMode); err != nil {
+		return fmt.Errorf("error making %s private: %w", path, err)
+	}
 
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useHome points the config file at a temporary home directory holding
+// contents with the given mode.
+func useHome(t *testing.T, contents string, mode os.FileMode) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(home, configFileName)
+	if err := os.WriteFile(path, []byte(contents), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func fileMode(t *testing.T, path string) os.FileMode {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Mode().Perm()
+}
+
+func TestSetUserMakesFilePrivate(t *testing.T) {
+	path := useHome(t, `{"Db_url":"postgres://localhost/gator"}`, 0644)
+	if err := Read().SetUser("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if mode := fileMode(t, path); mode != 0600 {
+		t.Errorf("mode = %o, want 600", mode)
+	}
+	if got := Read().Current_user_name; got != "alice" {
+		t.Errorf("Current_user_name = %q, want alice", got)
+	}
+}
+
+func TestReadMakesFileWithPasswordPrivate(t *testing.T) {
+	path := useHome(t, `{"Smtp_password":"hunter2"}`, 0644)
+	Read()
+	if mode := fileMode(t, path); mode != 0600 {
+		t.Errorf("mode = %o, want 600", mode)
+	}
+}
+
+func TestReadLeavesFileWithoutPassword(t *testing.T) {
+	path := useHome(t, `{"Current_user_name":"alice"}`, 0644)
+	Read()
+	if mode := fileMode(t, path); mode != 0644 {
+		t.Errorf("mode = %o, want 644", mode)
+	}
+}
+
+func TestSMTPPassword(t *testing.T) {
+	c := Config{Smtp_password: "from file"}
+	if got := c.SMTPPassword(); got != "from file" {
+		t.Errorf("SMTPPassword() = %q, want %q", got, "from file")
+	}
+	t.Setenv(smtpPasswordEnv, "from env")
+	if got := c.SMTPPassword(); got != "from env" {
+		t.Errorf("SMTPPassword() = %q, want %q", got, "from env")
+	}
+}
@@ -13,6 +13,24 @@ import (
 	"github.com/google/uuid"
 )
 
+const copyFeedFollows = `-- name: CopyFeedFollows :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+SELECT gen_random_uuid(), NOW(), NOW(), user_id, $1
+FROM feed_follows
+WHERE feed_id = $2
+ON CONFLICT (user_id, feed_id) DO NOTHING
+`
+
+type CopyFeedFollowsParams struct {
+	ToFeedID   uuid.NullUUID
+	FromFeedID uuid.NullUUID
+}
+
+func (q *Queries) CopyFeedFollows(ctx context.Context, arg CopyFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, copyFeedFollows, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
     INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
@@ -75,12 +93,34 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 	return i, err
 }
 
+const deleteFeedFollow = `-- name: DeleteFeedFollow :execrows
+DELETE FROM feed_follows
+WHERE user_id = $1
+AND feed_id = $2
+`
+
+type DeleteFeedFollowParams struct {
+	UserID uuid.NullUUID
+	FeedID uuid.NullUUID
+}
+
+func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollow, arg.UserID, arg.FeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteFeedFollowForUser = `-- name: DeleteFeedFollowForUser :exec
 DELETE FROM feed_follows ff
 WHERE ff.user_id = $1
-AND ff.feed_id = (
+AND ff.feed_id IN (
     SELECT feeds.id FROM feeds
     WHERE feeds.url = $2
+    UNION
+    SELECT feed_aliases.feed_id FROM feed_aliases
+    WHERE feed_aliases.url = $2
 )
 `
 
@@ -96,7 +136,7 @@ func (q *Queries) DeleteFeedFollowForUser(ctx context.Context, arg DeleteFeedFol
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT
-    ff.id, ff.created_at, ff.updated_at, ff.user_id, feed_id, u.id, u.created_at, u.updated_at, u.name, f.id, f.created_at, f.updated_at, f.name, url, f.user_id, last_fetched_at,
+    ff.id, ff.created_at, ff.updated_at, ff.user_id, feed_id, u.id, u.created_at, u.updated_at, u.name, password_hash, feed_secret, fever_key_hash, f.id, f.created_at, f.updated_at, f.name, url, f.user_id, last_fetched_at, deactivated_at, next_fetch_at, poll_interval_seconds, interval_override_seconds, f.number,
     f.name AS feed_name,
     u.name AS user_name
 FROM feed_follows ff
@@ -105,27 +145,36 @@ ON ff.user_id = u.id
 INNER JOIN feeds f
 ON feed_id = f.id
 WHERE ff.user_id = $1
+ORDER BY ff.created_at, ff.id
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	UserID        uuid.NullUUID
-	FeedID        uuid.NullUUID
-	ID_2          uuid.UUID
-	CreatedAt_2   time.Time
-	UpdatedAt_2   time.Time
-	Name          string
-	ID_3          uuid.UUID
-	CreatedAt_3   time.Time
-	UpdatedAt_3   time.Time
-	Name_2        string
-	Url           string
-	UserID_2      uuid.NullUUID
-	LastFetchedAt sql.NullTime
-	FeedName      string
-	UserName      string
+	ID                      uuid.UUID
+	CreatedAt               time.Time
+	UpdatedAt               time.Time
+	UserID                  uuid.NullUUID
+	FeedID                  uuid.NullUUID
+	ID_2                    uuid.UUID
+	CreatedAt_2             time.Time
+	UpdatedAt_2             time.Time
+	Name                    string
+	PasswordHash            sql.NullString
+	FeedSecret              sql.NullString
+	FeverKeyHash            sql.NullString
+	ID_3                    uuid.UUID
+	CreatedAt_3             time.Time
+	UpdatedAt_3             time.Time
+	Name_2                  string
+	Url                     string
+	UserID_2                uuid.NullUUID
+	LastFetchedAt           sql.NullTime
+	DeactivatedAt           sql.NullTime
+	NextFetchAt             sql.NullTime
+	PollIntervalSeconds     sql.NullInt32
+	IntervalOverrideSeconds sql.NullInt32
+	Number                  int64
+	FeedName                string
+	UserName                string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.NullUUID) ([]GetFeedFollowsForUserRow, error) {
@@ -147,6 +196,9 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.NullUUI
 			&i.CreatedAt_2,
 			&i.UpdatedAt_2,
 			&i.Name,
+			&i.PasswordHash,
+			&i.FeedSecret,
+			&i.FeverKeyHash,
 			&i.ID_3,
 			&i.CreatedAt_3,
 			&i.UpdatedAt_3,
@@ -154,6 +206,11 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.NullUUI
 			&i.Url,
 			&i.UserID_2,
 			&i.LastFetchedAt,
+			&i.DeactivatedAt,
+			&i.NextFetchAt,
+			&i.PollIntervalSeconds,
+			&i.IntervalOverrideSeconds,
+			&i.Number,
 			&i.FeedName,
 			&i.UserName,
 		); err != nil {
@@ -169,3 +226,63 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.NullUUI
 	}
 	return items, nil
 }
+
+const getFollowedFeedsWithUnreadCounts = `-- name: GetFollowedFeedsWithUnreadCounts :many
+SELECT
+    feeds.id,
+    feeds.name,
+    feeds.url,
+    feeds.deactivated_at,
+    COUNT(posts.id) AS post_count,
+    COUNT(posts.id) FILTER (WHERE post_reads.post_id IS NULL) AS unread_count
+FROM feed_follows
+INNER JOIN feeds
+ON feed_follows.feed_id = feeds.id
+LEFT JOIN posts
+ON posts.feed_id = feeds.id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+GROUP BY feeds.id
+ORDER BY feeds.name
+`
+
+type GetFollowedFeedsWithUnreadCountsRow struct {
+	ID            uuid.UUID
+	Name          string
+	Url           string
+	DeactivatedAt sql.NullTime
+	PostCount     int64
+	UnreadCount   int64
+}
+
+func (q *Queries) GetFollowedFeedsWithUnreadCounts(ctx context.Context, userID uuid.NullUUID) ([]GetFollowedFeedsWithUnreadCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedFeedsWithUnreadCounts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowedFeedsWithUnreadCountsRow
+	for rows.Next() {
+		var i GetFollowedFeedsWithUnreadCountsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.DeactivatedAt,
+			&i.PostCount,
+			&i.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,7 +23,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, deactivated_at, next_fetch_at, poll_interval_seconds, interval_override_seconds, number
 `
 
 type CreateFeedParams struct {
@@ -52,13 +53,153 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.DeactivatedAt,
+		&i.NextFetchAt,
+		&i.PollIntervalSeconds,
+		&i.IntervalOverrideSeconds,
+		&i.Number,
+	)
+	return i, err
+}
+
+const createFeedAlias = `-- name: CreateFeedAlias :exec
+INSERT INTO feed_aliases (id, created_at, url, feed_id)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (url) DO NOTHING
+`
+
+type CreateFeedAliasParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Url       string
+	FeedID    uuid.UUID
+}
+
+func (q *Queries) CreateFeedAlias(ctx context.Context, arg CreateFeedAliasParams) error {
+	_, err := q.db.ExecContext(ctx, createFeedAlias,
+		arg.ID,
+		arg.CreatedAt,
+		arg.Url,
+		arg.FeedID,
+	)
+	return err
+}
+
+const deactivateFeed = `-- name: DeactivateFeed :exec
+UPDATE feeds
+SET deactivated_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) DeactivateFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deactivateFeed, id)
+	return err
+}
+
+const getDueFeeds = `-- name: GetDueFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, deactivated_at, next_fetch_at, poll_interval_seconds, interval_override_seconds, number 
+FROM feeds
+WHERE deactivated_at IS NULL
+AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+LIMIT $1
+`
+
+func (q *Queries) GetDueFeeds(ctx context.Context, limit int32) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getDueFeeds, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.DeactivatedAt,
+			&i.NextFetchAt,
+			&i.PollIntervalSeconds,
+			&i.IntervalOverrideSeconds,
+			&i.Number,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeed = `-- name: GetFeed :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, deactivated_at, next_fetch_at, poll_interval_seconds, interval_override_seconds, number FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeed(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeed, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.DeactivatedAt,
+		&i.NextFetchAt,
+		&i.PollIntervalSeconds,
+		&i.IntervalOverrideSeconds,
+		&i.Number,
+	)
+	return i, err
+}
+
+const getFeedByNumber = `-- name: GetFeedByNumber :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, deactivated_at, next_fetch_at, poll_interval_seconds, interval_override_seconds, number FROM feeds
+WHERE number = $1
+`
+
+func (q *Queries) GetFeedByNumber(ctx context.Context, number int64) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByNumber, number)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.DeactivatedAt,
+		&i.NextFetchAt,
+		&i.PollIntervalSeconds,
+		&i.IntervalOverrideSeconds,
+		&i.Number,
 	)
 	return i, err
 }
 
 const getFeedFromURL = `-- name: GetFeedFromURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, deactivated_at, next_fetch_at, poll_interval_seconds, interval_override_seconds, number FROM feeds
 WHERE url = $1
+OR id = (
+    SELECT feed_id FROM feed_aliases
+    WHERE feed_aliases.url = $1
+)
+ORDER BY (url = $1) DESC
+LIMIT 1
 `
 
 func (q *Queries) GetFeedFromURL(ctx context.Context, url string) (Feed, error) {
@@ -72,12 +213,18 @@ func (q *Queries) GetFeedFromURL(ctx context.Context, url string) (Feed, error)
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.DeactivatedAt,
+		&i.NextFetchAt,
+		&i.PollIntervalSeconds,
+		&i.IntervalOverrideSeconds,
+		&i.Number,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, deactivated_at, next_fetch_at, poll_interval_seconds, interval_override_seconds, number FROM feeds
+ORDER BY created_at, id
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -97,6 +244,11 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.DeactivatedAt,
+			&i.NextFetchAt,
+			&i.PollIntervalSeconds,
+			&i.IntervalOverrideSeconds,
+			&i.Number,
 		); err != nil {
 			return nil, err
 		}
@@ -111,26 +263,48 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at 
-FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1
+const getFollowedFeeds = `-- name: GetFollowedFeeds :many
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.deactivated_at, feeds.next_fetch_at, feeds.poll_interval_seconds, feeds.interval_override_seconds, feeds.number FROM feeds
+INNER JOIN feed_follows
+ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.name
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
-	)
-	return i, err
+func (q *Queries) GetFollowedFeeds(ctx context.Context, userID uuid.NullUUID) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedFeeds, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.DeactivatedAt,
+			&i.NextFetchAt,
+			&i.PollIntervalSeconds,
+			&i.IntervalOverrideSeconds,
+			&i.Number,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :exec
@@ -143,3 +317,54 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
 	return err
 }
+
+const scheduleFeedFetch = `-- name: ScheduleFeedFetch :exec
+UPDATE feeds
+SET next_fetch_at = NOW() + ($1::int * INTERVAL '1 second'),
+    poll_interval_seconds = $2,
+    updated_at = NOW()
+WHERE id = $3
+`
+
+type ScheduleFeedFetchParams struct {
+	DelaySeconds        int32
+	PollIntervalSeconds sql.NullInt32
+	ID                  uuid.UUID
+}
+
+func (q *Queries) ScheduleFeedFetch(ctx context.Context, arg ScheduleFeedFetchParams) error {
+	_, err := q.db.ExecContext(ctx, scheduleFeedFetch, arg.DelaySeconds, arg.PollIntervalSeconds, arg.ID)
+	return err
+}
+
+const setFeedIntervalOverride = `-- name: SetFeedIntervalOverride :exec
+UPDATE feeds
+SET interval_override_seconds = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedIntervalOverrideParams struct {
+	ID                      uuid.UUID
+	IntervalOverrideSeconds sql.NullInt32
+}
+
+func (q *Queries) SetFeedIntervalOverride(ctx context.Context, arg SetFeedIntervalOverrideParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedIntervalOverride, arg.ID, arg.IntervalOverrideSeconds)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedURLParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.ID, arg.Url)
+	return err
+}
@@ -11,14 +11,49 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UserID      uuid.UUID
+	Name        string
+	TokenHash   string
+	TokenPrefix string
+	LastUsedAt  sql.NullTime
+}
+
+type Digest struct {
+	UserID     uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Email      string
+	Frequency  string
+	TimeZone   string
+	SendMinute int32
+	Weekday    int32
+	LastSentAt sql.NullTime
+	NextSendAt time.Time
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.NullUUID
-	LastFetchedAt sql.NullTime
+	ID                      uuid.UUID
+	CreatedAt               time.Time
+	UpdatedAt               time.Time
+	Name                    string
+	Url                     string
+	UserID                  uuid.NullUUID
+	LastFetchedAt           sql.NullTime
+	DeactivatedAt           sql.NullTime
+	NextFetchAt             sql.NullTime
+	PollIntervalSeconds     sql.NullInt32
+	IntervalOverrideSeconds sql.NullInt32
+	Number                  int64
+}
+
+type FeedAlias struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Url       string
+	FeedID    uuid.UUID
 }
 
 type FeedFollow struct {
@@ -38,11 +73,77 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.NullUUID
+	ContentHtml sql.NullString
+	ContentText sql.NullString
+	Number      int64
+	Author      sql.NullString
+	Categories  []string
+}
+
+type PostHide struct {
+	UserID   uuid.UUID
+	PostID   uuid.UUID
+	HiddenAt time.Time
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PostStar struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
+}
+
+type PostTag struct {
+	UserID   uuid.UUID
+	PostID   uuid.UUID
+	Tag      string
+	TaggedAt time.Time
+}
+
+type Rule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	Pattern   string
+	IsRegex   bool
+	Action    string
+	Tag       sql.NullString
 }
 
 type User struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
+	FeedSecret   sql.NullString
+	FeverKeyHash sql.NullString
+}
+
+type Webhook struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	UserID    uuid.UUID
+	Url       string
+	FeedID    uuid.NullUUID
+	Keyword   sql.NullString
+	Secret    string
+}
+
+type WebhookDelivery struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	WebhookID  uuid.UUID
+	FeedID     uuid.UUID
+	Posts      int32
+	Attempts   int32
+	StatusCode sql.NullInt32
+	Error      sql.NullString
 }
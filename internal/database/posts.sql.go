@@ -11,15 +11,118 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id) 
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+const countPostsForUser = `-- name: CountPostsForUser :one
+SELECT COUNT(*)
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+`
+
+func (q *Queries) CountPostsForUser(ctx context.Context, userID uuid.NullUUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsForUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getFeedPostingStats = `-- name: GetFeedPostingStats :one
+SELECT
+    COUNT(*) AS post_count,
+    COALESCE(
+        EXTRACT(EPOCH FROM MAX(published_at) - MIN(published_at)) / NULLIF(COUNT(*) - 1, 0),
+        0
+    )::float8 AS avg_gap_seconds
+FROM (
+    SELECT published_at FROM posts
+    WHERE feed_id = $1
+    AND published_at IS NOT NULL
+    ORDER BY published_at DESC
+    LIMIT 20
+) recent_posts
 `
 
-type CreatePostParams struct {
+type GetFeedPostingStatsRow struct {
+	PostCount     int64
+	AvgGapSeconds float64
+}
+
+func (q *Queries) GetFeedPostingStats(ctx context.Context, feedID uuid.NullUUID) (GetFeedPostingStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedPostingStats, feedID)
+	var i GetFeedPostingStatsRow
+	err := row.Scan(&i.PostCount, &i.AvgGapSeconds)
+	return i, err
+}
+
+const getPostByURL = `-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content_html, content_text, number, author, categories FROM posts
+WHERE url = $1
+`
+
+func (q *Queries) GetPostByURL(ctx context.Context, url string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURL, url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.ContentHtml,
+		&i.ContentText,
+		&i.Number,
+		&i.Author,
+		pq.Array(&i.Categories),
+	)
+	return i, err
+}
+
+const getPostForUser = `-- name: GetPostForUser :one
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content_html, posts.content_text, posts.number, posts.author, posts.categories,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = $1
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = $1
+    ) AS is_starred
+FROM posts
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+AND feed_follows.user_id = $1
+WHERE posts.id = $2
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+`
+
+type GetPostForUserParams struct {
+	UserID uuid.UUID
+	ID     uuid.UUID
+}
+
+type GetPostForUserRow struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
@@ -28,20 +131,20 @@ type CreatePostParams struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.NullUUID
+	ContentHtml sql.NullString
+	ContentText sql.NullString
+	Number      int64
+	Author      sql.NullString
+	Categories  []string
+	FeedName    string
+	FeedUrl     string
+	IsRead      bool
+	IsStarred   bool
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
-	)
-	var i Post
+func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams) (GetPostForUserRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUser, arg.UserID, arg.ID)
+	var i GetPostForUserRow
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
@@ -51,34 +154,259 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.ContentHtml,
+		&i.ContentText,
+		&i.Number,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.FeedName,
+		&i.FeedUrl,
+		&i.IsRead,
+		&i.IsStarred,
 	)
 	return i, err
 }
 
+const getPostNumbersForUser = `-- name: GetPostNumbersForUser :many
+SELECT posts.number
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND ($2::bool IS NULL OR (post_reads.read_at IS NOT NULL) = $2)
+AND ($3::bool IS NULL OR (post_stars.starred_at IS NOT NULL) = $3)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+ORDER BY posts.number
+`
+
+type GetPostNumbersForUserParams struct {
+	UserID  uuid.NullUUID
+	Read    sql.NullBool
+	Starred sql.NullBool
+}
+
+func (q *Queries) GetPostNumbersForUser(ctx context.Context, arg GetPostNumbersForUserParams) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getPostNumbersForUser, arg.UserID, arg.Read, arg.Starred)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var number int64
+		if err := rows.Scan(&number); err != nil {
+			return nil, err
+		}
+		items = append(items, number)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsByIDRange = `-- name: GetPostsByIDRange :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content_html, posts.content_text, posts.number, posts.author, posts.categories,
+    feeds.name AS feed_name
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+AND posts.id BETWEEN $2 AND $3
+ORDER BY posts.id
+LIMIT 2
+`
+
+type GetPostsByIDRangeParams struct {
+	UserID uuid.NullUUID
+	LowID  uuid.UUID
+	HighID uuid.UUID
+}
+
+type GetPostsByIDRangeRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.NullUUID
+	ContentHtml sql.NullString
+	ContentText sql.NullString
+	Number      int64
+	Author      sql.NullString
+	Categories  []string
+	FeedName    string
+}
+
+func (q *Queries) GetPostsByIDRange(ctx context.Context, arg GetPostsByIDRangeParams) ([]GetPostsByIDRangeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByIDRange, arg.UserID, arg.LowID, arg.HighID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsByIDRangeRow
+	for rows.Next() {
+		var i GetPostsByIDRangeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.ContentHtml,
+			&i.ContentText,
+			&i.Number,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT id, created_at, updated_at, title, url, description, published_at, feed_id FROM posts
-WHERE feed_id IN (
-    SELECT id FROM feeds
-    WHERE user_id = $1
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content_html, posts.content_text, posts.number, posts.author, posts.categories,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    (post_reads.read_at IS NOT NULL)::bool AS is_read,
+    (post_stars.starred_at IS NOT NULL)::bool AS is_starred
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND ($2::text IS NULL OR feeds.url = $2 OR feeds.name = $2)
+AND ($3::timestamp IS NULL OR posts.published_at >= $3)
+AND ($4::timestamp IS NULL OR posts.published_at < $4)
+AND (
+    $5::text IS NULL
+    OR posts.title ILIKE '%' || $5 || '%'
+    OR posts.description ILIKE '%' || $5 || '%'
+)
+AND ($6::bool IS NULL OR (post_reads.read_at IS NOT NULL) = $6)
+AND ($7::bool IS NULL OR (post_stars.starred_at IS NOT NULL) = $7)
+AND (
+    $8::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.post_id = posts.id
+        AND post_tags.user_id = feed_follows.user_id
+        AND post_tags.tag = $8
+    )
 )
-ORDER BY published_at DESC
-LIMIT $2
+AND (
+    $9::bool
+    OR NOT EXISTS (
+        SELECT 1 FROM post_hides
+        WHERE post_hides.post_id = posts.id
+        AND post_hides.user_id = feed_follows.user_id
+    )
+)
+ORDER BY
+    CASE WHEN $10::text = 'fetched' THEN posts.created_at END DESC,
+    CASE WHEN $10::text = 'feed' THEN feeds.name END ASC,
+    CASE WHEN $10::text = 'oldest' THEN posts.published_at END ASC,
+    posts.published_at DESC NULLS LAST,
+    posts.id
+LIMIT $11
+OFFSET $12
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.NullUUID
-	Limit  int32
+	UserID        uuid.NullUUID
+	Feed          sql.NullString
+	Since         sql.NullTime
+	Until         sql.NullTime
+	Search        sql.NullString
+	Read          sql.NullBool
+	Starred       sql.NullBool
+	Tag           sql.NullString
+	IncludeHidden bool
+	SortBy        string
+	RowLimit      int32
+	RowOffset     int32
 }
 
-func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+type GetPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.NullUUID
+	ContentHtml sql.NullString
+	ContentText sql.NullString
+	Number      int64
+	Author      sql.NullString
+	Categories  []string
+	FeedName    string
+	FeedUrl     string
+	IsRead      bool
+	IsStarred   bool
+}
+
+func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Feed,
+		arg.Since,
+		arg.Until,
+		arg.Search,
+		arg.Read,
+		arg.Starred,
+		arg.Tag,
+		arg.IncludeHidden,
+		arg.SortBy,
+		arg.RowLimit,
+		arg.RowOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []GetPostsForUserRow
 	for rows.Next() {
-		var i Post
+		var i GetPostsForUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -88,6 +416,15 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.ContentHtml,
+			&i.ContentText,
+			&i.Number,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.IsRead,
+			&i.IsStarred,
 		); err != nil {
 			return nil, err
 		}
@@ -101,3 +438,370 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getPostsForUserByNumberRange = `-- name: GetPostsForUserByNumberRange :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content_html, posts.content_text, posts.number, posts.author, posts.categories,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    (post_reads.read_at IS NOT NULL)::bool AS is_read,
+    (post_stars.starred_at IS NOT NULL)::bool AS is_starred
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND ($2::bigint IS NULL OR posts.number > $2)
+AND ($3::bigint IS NULL OR posts.number < $3)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+ORDER BY
+    CASE WHEN $3::bigint IS NULL THEN posts.number END ASC,
+    posts.number DESC
+LIMIT $4
+`
+
+type GetPostsForUserByNumberRangeParams struct {
+	UserID       uuid.NullUUID
+	AfterNumber  sql.NullInt64
+	BeforeNumber sql.NullInt64
+	RowLimit     int32
+}
+
+type GetPostsForUserByNumberRangeRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.NullUUID
+	ContentHtml sql.NullString
+	ContentText sql.NullString
+	Number      int64
+	Author      sql.NullString
+	Categories  []string
+	FeedName    string
+	FeedUrl     string
+	IsRead      bool
+	IsStarred   bool
+}
+
+func (q *Queries) GetPostsForUserByNumberRange(ctx context.Context, arg GetPostsForUserByNumberRangeParams) ([]GetPostsForUserByNumberRangeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUserByNumberRange,
+		arg.UserID,
+		arg.AfterNumber,
+		arg.BeforeNumber,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForUserByNumberRangeRow
+	for rows.Next() {
+		var i GetPostsForUserByNumberRangeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.ContentHtml,
+			&i.ContentText,
+			&i.Number,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.IsRead,
+			&i.IsStarred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUserByNumbers = `-- name: GetPostsForUserByNumbers :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content_html, posts.content_text, posts.number, posts.author, posts.categories,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    (post_reads.read_at IS NOT NULL)::bool AS is_read,
+    (post_stars.starred_at IS NOT NULL)::bool AS is_starred
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND posts.number = ANY($2::bigint[])
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+ORDER BY posts.published_at DESC NULLS LAST, posts.id
+`
+
+type GetPostsForUserByNumbersParams struct {
+	UserID  uuid.NullUUID
+	Numbers []int64
+}
+
+type GetPostsForUserByNumbersRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.NullUUID
+	ContentHtml sql.NullString
+	ContentText sql.NullString
+	Number      int64
+	Author      sql.NullString
+	Categories  []string
+	FeedName    string
+	FeedUrl     string
+	IsRead      bool
+	IsStarred   bool
+}
+
+func (q *Queries) GetPostsForUserByNumbers(ctx context.Context, arg GetPostsForUserByNumbersParams) ([]GetPostsForUserByNumbersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUserByNumbers, arg.UserID, pq.Array(arg.Numbers))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForUserByNumbersRow
+	for rows.Next() {
+		var i GetPostsForUserByNumbersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.ContentHtml,
+			&i.ContentText,
+			&i.Number,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.IsRead,
+			&i.IsStarred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnreadPostsSince = `-- name: GetUnreadPostsSince :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content_html, posts.content_text, posts.number, posts.author, posts.categories,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND post_reads.read_at IS NULL
+AND posts.created_at > $2
+ORDER BY feeds.name, posts.published_at DESC NULLS LAST, posts.id
+LIMIT $3
+`
+
+type GetUnreadPostsSinceParams struct {
+	UserID   uuid.NullUUID
+	Since    time.Time
+	RowLimit int32
+}
+
+type GetUnreadPostsSinceRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.NullUUID
+	ContentHtml sql.NullString
+	ContentText sql.NullString
+	Number      int64
+	Author      sql.NullString
+	Categories  []string
+	FeedName    string
+	FeedUrl     string
+}
+
+func (q *Queries) GetUnreadPostsSince(ctx context.Context, arg GetUnreadPostsSinceParams) ([]GetUnreadPostsSinceRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadPostsSince, arg.UserID, arg.Since, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadPostsSinceRow
+	for rows.Next() {
+		var i GetUnreadPostsSinceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.ContentHtml,
+			&i.ContentText,
+			&i.Number,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content_html, content_text, author, categories) 
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+ON CONFLICT (url) DO UPDATE
+SET title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    published_at = EXCLUDED.published_at,
+    content_html = EXCLUDED.content_html,
+    content_text = EXCLUDED.content_text,
+    author = EXCLUDED.author,
+    categories = EXCLUDED.categories,
+    updated_at = EXCLUDED.updated_at
+WHERE posts.title IS DISTINCT FROM EXCLUDED.title
+OR posts.description IS DISTINCT FROM EXCLUDED.description
+OR posts.published_at IS DISTINCT FROM EXCLUDED.published_at
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content_html, content_text, number, author, categories, (xmax = 0) AS inserted
+`
+
+type UpsertPostParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.NullUUID
+	ContentHtml sql.NullString
+	ContentText sql.NullString
+	Author      sql.NullString
+	Categories  []string
+}
+
+type UpsertPostRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.NullUUID
+	ContentHtml sql.NullString
+	ContentText sql.NullString
+	Number      int64
+	Author      sql.NullString
+	Categories  []string
+	Inserted    bool
+}
+
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (UpsertPostRow, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.ContentHtml,
+		arg.ContentText,
+		arg.Author,
+		pq.Array(arg.Categories),
+	)
+	var i UpsertPostRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.ContentHtml,
+		&i.ContentText,
+		&i.Number,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.Inserted,
+	)
+	return i, err
+}
@@ -7,27 +7,30 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5
 )
-RETURNING id, created_at, updated_at, name
+RETURNING id, created_at, updated_at, name, password_hash, feed_secret, fever_key_hash
 `
 
 type CreateUserParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -36,6 +39,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.Name,
+		arg.PasswordHash,
 	)
 	var i User
 	err := row.Scan(
@@ -43,12 +47,15 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.PasswordHash,
+		&i.FeedSecret,
+		&i.FeverKeyHash,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, password_hash, feed_secret, fever_key_hash FROM users
 WHERE name = $1
 `
 
@@ -60,12 +67,55 @@ func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.PasswordHash,
+		&i.FeedSecret,
+		&i.FeverKeyHash,
+	)
+	return i, err
+}
+
+const getUserByFeedSecret = `-- name: GetUserByFeedSecret :one
+SELECT id, created_at, updated_at, name, password_hash, feed_secret, fever_key_hash FROM users
+WHERE feed_secret = $1
+`
+
+func (q *Queries) GetUserByFeedSecret(ctx context.Context, feedSecret sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByFeedSecret, feedSecret)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.PasswordHash,
+		&i.FeedSecret,
+		&i.FeverKeyHash,
+	)
+	return i, err
+}
+
+const getUserByFeverKey = `-- name: GetUserByFeverKey :one
+SELECT id, created_at, updated_at, name, password_hash, feed_secret, fever_key_hash FROM users
+WHERE fever_key_hash = $1
+`
+
+func (q *Queries) GetUserByFeverKey(ctx context.Context, feverKeyHash sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByFeverKey, feverKeyHash)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.PasswordHash,
+		&i.FeedSecret,
+		&i.FeverKeyHash,
 	)
 	return i, err
 }
 
 const getUserFromID = `-- name: GetUserFromID :one
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, password_hash, feed_secret, fever_key_hash FROM users
 WHERE id = $1
 `
 
@@ -77,12 +127,16 @@ func (q *Queries) GetUserFromID(ctx context.Context, id uuid.UUID) (User, error)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.PasswordHash,
+		&i.FeedSecret,
+		&i.FeverKeyHash,
 	)
 	return i, err
 }
 
 const getUsers = `-- name: GetUsers :many
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, password_hash, feed_secret, fever_key_hash FROM users
+ORDER BY created_at, id
 `
 
 func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
@@ -99,6 +153,9 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Name,
+			&i.PasswordHash,
+			&i.FeedSecret,
+			&i.FeverKeyHash,
 		); err != nil {
 			return nil, err
 		}
@@ -121,3 +178,51 @@ func (q *Queries) Reset(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, reset)
 	return err
 }
+
+const setUserFeedSecret = `-- name: SetUserFeedSecret :exec
+UPDATE users
+SET feed_secret = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserFeedSecretParams struct {
+	ID         uuid.UUID
+	FeedSecret sql.NullString
+}
+
+func (q *Queries) SetUserFeedSecret(ctx context.Context, arg SetUserFeedSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setUserFeedSecret, arg.ID, arg.FeedSecret)
+	return err
+}
+
+const setUserFeverKey = `-- name: SetUserFeverKey :exec
+UPDATE users
+SET fever_key_hash = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserFeverKeyParams struct {
+	ID           uuid.UUID
+	FeverKeyHash sql.NullString
+}
+
+func (q *Queries) SetUserFeverKey(ctx context.Context, arg SetUserFeverKeyParams) error {
+	_, err := q.db.ExecContext(ctx, setUserFeverKey, arg.ID, arg.FeverKeyHash)
+	return err
+}
+
+const setUserPassword = `-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserPasswordParams struct {
+	ID           uuid.UUID
+	PasswordHash sql.NullString
+}
+
+func (q *Queries) SetUserPassword(ctx context.Context, arg SetUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, setUserPassword, arg.ID, arg.PasswordHash)
+	return err
+}
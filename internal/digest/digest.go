@@ -0,0 +1,181 @@
+// Package digest renders the unread posts of a period as an email and works
+// out when each user's next one is due.
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"unicode/utf8"
+
+	// digests are scheduled in the user's time zone, which must load even
+	// where the system has no zoneinfo
+	_ "time/tzdata"
+)
+
+const (
+	Daily  = "daily"
+	Weekly = "weekly"
+
+	// excerptLength is how many characters of a post's text are shown.
+	excerptLength = 280
+)
+
+// Frequencies lists the schedules a digest can be sent on.
+var Frequencies = []string{Daily, Weekly}
+
+// Digest is what a digest email is rendered from.
+type Digest struct {
+	User      string
+	Frequency string
+	// Since is when the period the digest covers started, in the user's
+	// time zone.
+	Since time.Time
+	Feeds []Feed
+	// More is set when there were more posts than the digest shows.
+	More bool
+}
+
+type Feed struct {
+	Name  string
+	URL   string
+	Posts []Post
+}
+
+type Post struct {
+	Title string
+	URL   string
+	// Published is zero when the post didn't say.
+	Published time.Time
+	Text      string
+}
+
+func ValidFrequency(frequency string) bool {
+	for _, valid := range Frequencies {
+		if frequency == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Period is how far back the first digest on a schedule reaches.
+func Period(frequency string) time.Duration {
+	if frequency == Weekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Next is the first time after after that a digest is due: minute minutes
+// past midnight in loc, on weekday for weekly digests. Days without that
+// time, around daylight saving changes, use the time it normalises to.
+func Next(after time.Time, frequency string, loc *time.Location, minute int, weekday time.Weekday) time.Time {
+	local := after.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), 0, minute, 0, 0, loc)
+	for !next.After(after) || (frequency == Weekly && next.Weekday() != weekday) {
+		next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, minute, 0, 0, loc)
+	}
+	return next
+}
+
+// Count is the number of posts in the digest.
+func (d Digest) Count() int {
+	count := 0
+	for _, feed := range d.Feeds {
+		count += len(feed.Posts)
+	}
+	return count
+}
+
+// Subject is the subject line of the digest email.
+func (d Digest) Subject() string {
+	noun := "posts"
+	if d.Count() == 1 {
+		noun = "post"
+	}
+	return fmt.Sprintf("Your %s gator digest: %d unread %s", d.Frequency, d.Count(), noun)
+}
+
+// Add puts a post under its feed, keeping feeds in the order first seen.
+func (d *Digest) Add(feedName, feedURL string, post Post) {
+	if n := len(d.Feeds); n == 0 || d.Feeds[n-1].URL != feedURL {
+		d.Feeds = append(d.Feeds, Feed{Name: feedName, URL: feedURL})
+	}
+	post.Text = excerpt(post.Text)
+	feed := &d.Feeds[len(d.Feeds)-1]
+	feed.Posts = append(feed.Posts, post)
+}
+
+// excerpt shortens text to about excerptLength characters, at a word
+// boundary, with newlines folded into spaces.
+func excerpt(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= excerptLength {
+		return text
+	}
+	cut := string([]rune(text)[:excerptLength])
+	if i := strings.LastIndexByte(cut, ' '); i > excerptLength/2 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
+
+var funcs = map[string]any{
+	"date": func(t time.Time) string { return t.Format("Mon 2 Jan 15:04") },
+}
+
+const textLayout = `Hi {{.User}},
+
+{{if .Feeds}}here are the posts that came in since {{date .Since}} and you haven't read yet.
+{{range .Feeds}}
+== {{.Name}} ==
+{{range .Posts}}
+{{.Title}}{{if not .Published.IsZero}} ({{date .Published}}){{end}}
+{{.URL}}
+{{if .Text}}{{.Text}}
+{{end}}{{end}}{{end}}{{if .More}}
+There are more unread posts waiting in gator.
+{{end}}{{else}}nothing new came in since {{date .Since}}, you're all caught up.
+{{end}}
+--
+gator
+`
+
+const htmlLayout = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Subject}}</title></head>
+<body style="font-family: sans-serif; max-width: 40em; margin: auto; color: #222">
+<p>Hi {{.User}},</p>
+{{if .Feeds}}<p>here are the posts that came in since {{date .Since}} and you haven't read yet.</p>
+{{range .Feeds}}<h2 style="font-size: 1.1em; border-bottom: 1px solid #ccc"><a href="{{.URL}}" style="color: inherit">{{.Name}}</a></h2>
+{{range .Posts}}<p><a href="{{.URL}}"><strong>{{.Title}}</strong></a>{{if not .Published.IsZero}} <small style="color: #666">{{date .Published}}</small>{{end}}{{if .Text}}<br>
+{{.Text}}{{end}}</p>
+{{end}}{{end}}{{if .More}}<p><em>There are more unread posts waiting in gator.</em></p>
+{{end}}{{else}}<p>nothing new came in since {{date .Since}}, you're all caught up.</p>
+{{end}}<p style="color: #666"><small>Sent by gator. Change or stop it with <code>gator digest</code>.</small></p>
+</body>
+</html>
+`
+
+var (
+	textTemplate = texttemplate.Must(texttemplate.New("text").Funcs(funcs).Parse(textLayout))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(htmlLayout))
+)
+
+// Text renders the plain text part of the digest.
+func (d Digest) Text() (string, error) {
+	var buf bytes.Buffer
+	err := textTemplate.Execute(&buf, d)
+	return buf.String(), err
+}
+
+// HTML renders the HTML part of the digest.
+func (d Digest) HTML() (string, error) {
+	var buf bytes.Buffer
+	err := htmlTemplate.Execute(&buf, d)
+	return buf.String(), err
+}
@@ -0,0 +1,173 @@
+package digest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		at0700 = 7 * 60
+		at0230 = 2*60 + 30
+	)
+	tests := []struct {
+		name      string
+		after     time.Time
+		frequency string
+		loc       *time.Location
+		minute    int
+		weekday   time.Weekday
+		want      time.Time
+	}{
+		{
+			name:      "daily later today",
+			after:     time.Date(2024, time.March, 6, 5, 0, 0, 0, berlin),
+			frequency: Daily,
+			loc:       berlin,
+			minute:    at0700,
+			want:      time.Date(2024, time.March, 6, 7, 0, 0, 0, berlin),
+		},
+		{
+			name:      "daily already past today",
+			after:     time.Date(2024, time.March, 6, 9, 0, 0, 0, berlin),
+			frequency: Daily,
+			loc:       berlin,
+			minute:    at0700,
+			want:      time.Date(2024, time.March, 7, 7, 0, 0, 0, berlin),
+		},
+		{
+			name:      "daily exactly at the time",
+			after:     time.Date(2024, time.March, 6, 7, 0, 0, 0, berlin),
+			frequency: Daily,
+			loc:       berlin,
+			minute:    at0700,
+			want:      time.Date(2024, time.March, 7, 7, 0, 0, 0, berlin),
+		},
+		{
+			name:      "daily across the end of a month",
+			after:     time.Date(2024, time.February, 29, 23, 0, 0, 0, berlin),
+			frequency: Daily,
+			loc:       berlin,
+			minute:    at0700,
+			want:      time.Date(2024, time.March, 1, 7, 0, 0, 0, berlin),
+		},
+		{
+			name:      "after given in another zone",
+			after:     time.Date(2024, time.March, 6, 21, 0, 0, 0, time.UTC),
+			frequency: Daily,
+			loc:       tokyo,
+			minute:    at0700,
+			want:      time.Date(2024, time.March, 7, 7, 0, 0, 0, tokyo),
+		},
+		{
+			name:      "daily into summer time keeps the wall clock",
+			after:     time.Date(2024, time.March, 30, 8, 0, 0, 0, berlin),
+			frequency: Daily,
+			loc:       berlin,
+			minute:    at0700,
+			want:      time.Date(2024, time.March, 31, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "daily out of summer time keeps the wall clock",
+			after:     time.Date(2024, time.October, 26, 8, 0, 0, 0, berlin),
+			frequency: Daily,
+			loc:       berlin,
+			minute:    at0700,
+			want:      time.Date(2024, time.October, 27, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "time skipped by the change to summer time",
+			after:     time.Date(2024, time.March, 30, 8, 0, 0, 0, berlin),
+			frequency: Daily,
+			loc:       berlin,
+			minute:    at0230,
+			want:      time.Date(2024, time.March, 31, 3, 30, 0, 0, berlin),
+		},
+		{
+			name:      "day after the skipped time is back to normal",
+			after:     time.Date(2024, time.March, 31, 3, 30, 0, 0, berlin),
+			frequency: Daily,
+			loc:       berlin,
+			minute:    at0230,
+			want:      time.Date(2024, time.April, 1, 2, 30, 0, 0, berlin),
+		},
+		{
+			name:      "weekly later this week",
+			after:     time.Date(2024, time.March, 6, 12, 0, 0, 0, berlin), // Wednesday
+			frequency: Weekly,
+			loc:       berlin,
+			minute:    at0700,
+			weekday:   time.Friday,
+			want:      time.Date(2024, time.March, 8, 7, 0, 0, 0, berlin),
+		},
+		{
+			name:      "weekly earlier in the week",
+			after:     time.Date(2024, time.March, 6, 12, 0, 0, 0, berlin),
+			frequency: Weekly,
+			loc:       berlin,
+			minute:    at0700,
+			weekday:   time.Monday,
+			want:      time.Date(2024, time.March, 11, 7, 0, 0, 0, berlin),
+		},
+		{
+			name:      "weekly on the day before the time",
+			after:     time.Date(2024, time.March, 6, 6, 0, 0, 0, berlin),
+			frequency: Weekly,
+			loc:       berlin,
+			minute:    at0700,
+			weekday:   time.Wednesday,
+			want:      time.Date(2024, time.March, 6, 7, 0, 0, 0, berlin),
+		},
+		{
+			name:      "weekly on the day after the time",
+			after:     time.Date(2024, time.March, 6, 8, 0, 0, 0, berlin),
+			frequency: Weekly,
+			loc:       berlin,
+			minute:    at0700,
+			weekday:   time.Wednesday,
+			want:      time.Date(2024, time.March, 13, 7, 0, 0, 0, berlin),
+		},
+		{
+			name: "weekday is taken in the digest's zone",
+			// Sunday evening in New York is already Monday in UTC
+			after:     time.Date(2024, time.March, 4, 1, 0, 0, 0, time.UTC),
+			frequency: Weekly,
+			loc:       newYork,
+			minute:    21 * 60,
+			weekday:   time.Sunday,
+			want:      time.Date(2024, time.March, 3, 21, 0, 0, 0, newYork),
+		},
+		{
+			name:      "weekly across the change to summer time",
+			after:     time.Date(2024, time.March, 25, 12, 0, 0, 0, berlin),
+			frequency: Weekly,
+			loc:       berlin,
+			minute:    at0700,
+			weekday:   time.Monday,
+			want:      time.Date(2024, time.April, 1, 5, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Next(tt.after, tt.frequency, tt.loc, tt.minute, tt.weekday)
+			if !got.Equal(tt.want) {
+				t.Errorf("Next() = %s, want %s", got, tt.want.In(tt.loc))
+			}
+			if got.Location() != tt.loc {
+				t.Errorf("Next() is in %s, want %s", got.Location(), tt.loc)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Package hooks runs the commands users configure for each new post, with
+// the post in environment variables and as JSON on stdin.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// waitDelay is how long a hook that outlived its timeout gets to exit
+	// once killed, e.g. for children holding its output open.
+	waitDelay = 5 * time.Second
+	// stderrTail is how much of a failed hook's stderr is reported.
+	stderrTail = 512
+)
+
+// Post is what a hook is told about a post.
+type Post struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	// Published is nil when the post didn't say.
+	Published   *time.Time `json:"published,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	Categories  []string   `json:"categories"`
+	ContentHTML string     `json:"content_html"`
+	ContentText string     `json:"content_text"`
+	FeedName    string     `json:"feed_name"`
+	FeedURL     string     `json:"feed_url"`
+}
+
+// Env is the post as GATOR_* variables. Content is left to stdin, being
+// too large for the environment.
+func (p Post) Env() []string {
+	env := []string{
+		"GATOR_POST_ID=" + p.ID,
+		"GATOR_POST_TITLE=" + p.Title,
+		"GATOR_POST_URL=" + p.URL,
+		"GATOR_POST_AUTHOR=" + p.Author,
+		"GATOR_POST_CATEGORIES=" + strings.Join(p.Categories, ","),
+		"GATOR_FEED_NAME=" + p.FeedName,
+		"GATOR_FEED_URL=" + p.FeedURL,
+	}
+	if p.Published != nil {
+		env = append(env, "GATOR_POST_PUBLISHED="+p.Published.Format(time.RFC3339))
+	}
+	return env
+}
+
+// Runner runs hooks in the background, no more than a set number at once.
+type Runner struct {
+	timeout time.Duration
+	slots   chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	errs    []error
+}
+
+func NewRunner(timeout time.Duration, concurrency int) *Runner {
+	return &Runner{timeout: timeout, slots: make(chan struct{}, concurrency)}
+}
+
+// Start runs command for post once a slot is free. Cancelling ctx kills
+// hooks that are running and skips those still waiting.
+func (r *Runner) Start(ctx context.Context, command string, post Post) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		select {
+		case r.slots <- struct{}{}:
+		case <-ctx.Done():
+			r.fail(command, post, ctx.Err())
+			return
+		}
+		defer func() { <-r.slots }()
+		if err := r.run(ctx, command, post); err != nil {
+			r.fail(command, post, err)
+		}
+	}()
+}
+
+// Wait waits for every hook started so far and returns why any failed.
+func (r *Runner) Wait() []error {
+	r.wg.Wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	errs := r.errs
+	r.errs = nil
+	return errs
+}
+
+func (r *Runner) fail(command string, post Post, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, fmt.Errorf("hook %q failed for \"%s\": %w", command, post.Title, err))
+}
+
+func (r *Runner) run(ctx context.Context, command string, post Post) error {
+	input, err := json.Marshal(post)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, command)
+	killGroup(cmd)
+	cmd.Env = append(os.Environ(), post.Env()...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = waitDelay
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", r.timeout)
+	}
+	if err != nil {
+		if tail := lastBytes(strings.TrimSpace(stderr.String()), stderrTail); tail != "" {
+			return fmt.Errorf("%w: %s", err, tail)
+		}
+		return err
+	}
+	return nil
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+func lastBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return "…" + strings.ToValidUTF8(s[len(s)-n:], "")
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package hooks
+
+import "os/exec"
+
+// killGroup leaves cmd as is where there are no process groups; cancelling
+// it only kills the shell.
+func killGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,212 @@
+//go:build unix
+
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testPublished = time.Date(2024, time.March, 6, 10, 30, 0, 0, time.UTC)
+
+var testPost = Post{
+	ID:          "9b2c1f1e-3a7d-4c3e-8f57-0d6f4f0f1a2b",
+	Title:       `Go 1.23 "is" out`,
+	URL:         "https://go.dev/blog/go1.23",
+	Published:   &testPublished,
+	Author:      "The Go Team",
+	Categories:  []string{"releases", "go"},
+	ContentHTML: "<p>Range over functions.</p>",
+	ContentText: "Range over functions.",
+	FeedName:    "The Go Blog",
+	FeedURL:     "https://go.dev/blog/feed.atom",
+}
+
+// runOne runs a single hook and returns why it failed, if it did.
+func runOne(t *testing.T, timeout time.Duration, command string) error {
+	t.Helper()
+	r := NewRunner(timeout, 1)
+	r.Start(context.Background(), command, testPost)
+	errs := r.Wait()
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		t.Fatalf("Wait() = %v, want at most one error", errs)
+		return nil
+	}
+}
+
+func TestHookEnvironment(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+	if err := runOne(t, 5*time.Second, "env > "+out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			env[key] = value
+		}
+	}
+	want := map[string]string{
+		"GATOR_POST_ID":         testPost.ID,
+		"GATOR_POST_TITLE":      testPost.Title,
+		"GATOR_POST_URL":        testPost.URL,
+		"GATOR_POST_AUTHOR":     testPost.Author,
+		"GATOR_POST_CATEGORIES": "releases,go",
+		"GATOR_POST_PUBLISHED":  "2024-03-06T10:30:00Z",
+		"GATOR_FEED_NAME":       testPost.FeedName,
+		"GATOR_FEED_URL":        testPost.FeedURL,
+	}
+	for key, value := range want {
+		if env[key] != value {
+			t.Errorf("%s = %q, want %q", key, env[key], value)
+		}
+	}
+	if env["PATH"] == "" {
+		t.Error("PATH not passed on to the hook")
+	}
+}
+
+func TestHookStdin(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "stdin")
+	if err := runOne(t, 5*time.Second, "cat > "+out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Post
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("stdin isn't JSON: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(got, testPost) {
+		t.Errorf("stdin = %+v, want %+v", got, testPost)
+	}
+}
+
+func TestHookFailure(t *testing.T) {
+	err := runOne(t, 5*time.Second, "echo 'no such feed' >&2; exit 3")
+	if err == nil {
+		t.Fatal("hook exiting with 3 succeeded")
+	}
+	if !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "no such feed") {
+		t.Errorf("error = %q, want the exit status and stderr", err)
+	}
+}
+
+func TestHookTimeoutKillsProcessGroup(t *testing.T) {
+	dir := t.TempDir()
+	survived := filepath.Join(dir, "survived")
+	// the background child also holds stderr open, which would keep Run
+	// waiting if only the shell were killed
+	command := "(sleep 1; touch " + survived + ") & sleep 5"
+
+	start := time.Now()
+	err := runOne(t, 200*time.Millisecond, command)
+	elapsed := time.Since(start)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Fatalf("error = %v, want a timeout", err)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("hook ran for %s after a 200ms timeout", elapsed)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(survived); err == nil {
+		t.Error("a child of the hook outlived the timeout")
+	}
+}
+
+func TestRunnerConcurrency(t *testing.T) {
+	const (
+		hooks       = 6
+		concurrency = 2
+		hookTime    = 200 * time.Millisecond
+	)
+	dir := t.TempDir()
+	running := filepath.Join(dir, "running")
+	if err := os.Mkdir(running, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// each hook notes how many hooks, itself included, it saw running
+	command := "touch " + running + "/$$; ls " + running + " | wc -l > " + dir + "/seen.$$; sleep 0.2; rm " + running + "/$$"
+
+	r := NewRunner(5*time.Second, concurrency)
+	start := time.Now()
+	for i := 0; i < hooks; i++ {
+		r.Start(context.Background(), command, testPost)
+	}
+	if errs := r.Wait(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if elapsed, least := time.Since(start), hooks/concurrency*hookTime; elapsed < least {
+		t.Errorf("%d hooks took %s, want at least %s with %d at a time", hooks, elapsed, least, concurrency)
+	}
+
+	seen, err := filepath.Glob(filepath.Join(dir, "seen.*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != hooks {
+		t.Fatalf("%d hooks ran, want %d", len(seen), hooks)
+	}
+	for _, path := range seen {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n > concurrency {
+			t.Errorf("a hook saw %d hooks running, want at most %d", n, concurrency)
+		}
+	}
+}
+
+func TestRunnerCancelled(t *testing.T) {
+	dir := t.TempDir()
+	r := NewRunner(5*time.Second, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	started := filepath.Join(dir, "started")
+	r.Start(ctx, "touch "+started+"; sleep 5", testPost)
+	// the second hook has to wait for the first one's slot
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(started); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the first hook didn't start")
+		}
+	}
+	r.Start(ctx, "touch "+filepath.Join(dir, "ran"), testPost)
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	errs := r.Wait()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Wait() took %s after cancelling", elapsed)
+	}
+	if len(errs) != 2 {
+		t.Errorf("Wait() = %v, want an error for each hook", errs)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); err == nil {
+		t.Error("a hook waiting for a slot ran after cancelling")
+	}
+}
@@ -0,0 +1,515 @@
+// Package htmltext renders HTML fragments, such as post descriptions, as
+// readable terminal text.
+package htmltext
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Options controls how text is laid out.
+type Options struct {
+	// Width wraps lines at this many columns; 0 disables wrapping.
+	Width int
+	// Color enables ANSI bold, italic and underline styling.
+	Color bool
+}
+
+type style int
+
+const (
+	styleBold style = 1 << iota
+	styleItalic
+	styleUnderline
+)
+
+type word struct {
+	text  string
+	style style
+	// space is true when whitespace separated this word from the previous one.
+	space bool
+}
+
+type list struct {
+	ordered bool
+	next    int
+}
+
+type renderer struct {
+	opts Options
+
+	lines   []string
+	needGap bool
+
+	words        []word
+	pendingSpace bool
+	style        style
+
+	quoteDepth int
+	lists      []list
+	// bullet is put in front of the first line of the current list item.
+	bullet string
+
+	pre     bool
+	preText strings.Builder
+
+	links []string
+}
+
+// Render converts an HTML fragment to plain text: paragraphs separated by
+// blank lines, bulleted and numbered lists, quotes, and links numbered as
+// footnotes listed at the end.
+func Render(fragment string, opts Options) string {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		return fragment
+	}
+
+	r := &renderer{opts: opts}
+	for _, n := range nodes {
+		r.walk(n)
+	}
+	r.endBlock()
+
+	for len(r.lines) > 0 && r.lines[len(r.lines)-1] == "" {
+		r.lines = r.lines[:len(r.lines)-1]
+	}
+	if len(r.links) > 0 {
+		r.lines = append(r.lines, "")
+		for i, link := range r.links {
+			r.lines = append(r.lines, fmt.Sprintf("[%d] %s", i+1, link))
+		}
+	}
+	return strings.Join(r.lines, "\n")
+}
+
+// Line renders a fragment on a single unstyled line, for titles and the like.
+func Line(fragment string) string {
+	return strings.Join(strings.Fields(Render(fragment, Options{})), " ")
+}
+
+// Fit cuts a rendered line to width columns, marking the cut with an
+// ellipsis, and pads it with spaces to exactly that width. ANSI styling takes
+// no room and is reset when the line is cut.
+func Fit(line string, width int) string {
+	if width < 1 {
+		return ""
+	}
+	limit := width
+	if visibleWidth(line) > width {
+		limit = width - 1
+	}
+
+	var b strings.Builder
+	used := 0
+	styled := false
+	for i := 0; i < len(line); {
+		if n := escapeLen(line[i:]); n > 0 {
+			b.WriteString(line[i : i+n])
+			styled = true
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		if used+runeWidth(r) > limit {
+			break
+		}
+		b.WriteRune(r)
+		used += runeWidth(r)
+		i += size
+	}
+	if limit < width {
+		b.WriteString("…")
+		used++
+	}
+	if styled {
+		b.WriteString("\x1b[0m")
+	}
+	b.WriteString(strings.Repeat(" ", width-used))
+	return b.String()
+}
+
+// visibleWidth is displayWidth ignoring ANSI styling.
+func visibleWidth(line string) int {
+	width := 0
+	for i := 0; i < len(line); {
+		if n := escapeLen(line[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		width += runeWidth(r)
+		i += size
+	}
+	return width
+}
+
+// escapeLen returns the length of the ANSI sequence s starts with, or 0.
+func escapeLen(s string) int {
+	if !strings.HasPrefix(s, "\x1b[") {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (r *renderer) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		r.walkChildren(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Title, atom.Noscript, atom.Template:
+		return
+	case atom.Br:
+		r.endLine()
+	case atom.Hr:
+		r.endBlock()
+		r.gap()
+		width := 40
+		if r.opts.Width > 0 {
+			width = min(width, r.opts.Width)
+		}
+		r.emit(strings.Repeat("─", width))
+		r.gap()
+	case atom.Img:
+		alt := strings.TrimSpace(attr(n, "alt"))
+		if alt == "" {
+			r.addWord("[image]", false)
+		} else {
+			r.addWord("[image: "+strings.Join(strings.Fields(alt), " ")+"]", true)
+		}
+	case atom.P, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Table, atom.Figure, atom.Dl:
+		r.endBlock()
+		r.gap()
+		restore := r.style
+		if isHeading(n.DataAtom) {
+			r.style |= styleBold
+		}
+		r.walkChildren(n)
+		r.style = restore
+		r.endBlock()
+		r.gap()
+	case atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Figcaption, atom.Tr, atom.Dt, atom.Dd:
+		r.endBlock()
+		r.walkChildren(n)
+		r.endBlock()
+	case atom.Blockquote:
+		r.endBlock()
+		r.gap()
+		r.quoteDepth++
+		r.walkChildren(n)
+		r.endBlock()
+		r.quoteDepth--
+		r.gap()
+	case atom.Pre:
+		r.endBlock()
+		r.gap()
+		r.pre = true
+		r.walkChildren(n)
+		r.pre = false
+		r.endPre()
+		r.gap()
+	case atom.Ul, atom.Ol:
+		r.endBlock()
+		if len(r.lists) == 0 {
+			r.gap()
+		}
+		start := 1
+		if value, err := strconv.Atoi(attr(n, "start")); err == nil {
+			start = value
+		}
+		r.lists = append(r.lists, list{ordered: n.DataAtom == atom.Ol, next: start})
+		r.walkChildren(n)
+		r.endBlock()
+		r.lists = r.lists[:len(r.lists)-1]
+		if len(r.lists) == 0 {
+			r.gap()
+		}
+	case atom.Li:
+		r.endBlock()
+		r.bullet = "• "
+		if len(r.lists) > 0 {
+			current := &r.lists[len(r.lists)-1]
+			if current.ordered {
+				r.bullet = fmt.Sprintf("%d. ", current.next)
+				current.next++
+			}
+		}
+		r.walkChildren(n)
+		r.endBlock()
+	case atom.A:
+		restore := r.style
+		r.style |= styleUnderline
+		r.walkChildren(n)
+		r.style = restore
+		href := strings.TrimSpace(attr(n, "href"))
+		if href != "" && !strings.HasPrefix(href, "#") && !strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			r.links = append(r.links, href)
+			r.addWord(fmt.Sprintf("[%d]", len(r.links)), false)
+		}
+	case atom.B, atom.Strong:
+		restore := r.style
+		r.style |= styleBold
+		r.walkChildren(n)
+		r.style = restore
+	case atom.I, atom.Em, atom.Cite:
+		restore := r.style
+		r.style |= styleItalic
+		r.walkChildren(n)
+		r.style = restore
+	case atom.Td, atom.Th:
+		r.pendingSpace = true
+		r.walkChildren(n)
+		r.pendingSpace = true
+	default:
+		r.walkChildren(n)
+	}
+}
+
+func (r *renderer) walkChildren(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		r.walk(child)
+	}
+}
+
+func (r *renderer) text(s string) {
+	if r.pre {
+		r.preText.WriteString(s)
+		return
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" {
+			r.pendingSpace = true
+		}
+		return
+	}
+	first, _ := utf8.DecodeRuneInString(s)
+	last, _ := utf8.DecodeLastRuneInString(s)
+	for i, field := range fields {
+		r.addWord(field, i > 0 || unicode.IsSpace(first))
+	}
+	r.pendingSpace = unicode.IsSpace(last)
+}
+
+func (r *renderer) addWord(text string, space bool) {
+	if r.pre {
+		r.preText.WriteString(text)
+		return
+	}
+	r.words = append(r.words, word{text: text, style: r.style, space: space || r.pendingSpace})
+	r.pendingSpace = false
+}
+
+func (r *renderer) gap() {
+	r.needGap = true
+}
+
+// emit adds a finished line, preceded by a blank line if one is owed.
+func (r *renderer) emit(line string) {
+	if r.needGap && len(r.lines) > 0 && r.lines[len(r.lines)-1] != "" {
+		r.lines = append(r.lines, "")
+	}
+	r.needGap = false
+	r.lines = append(r.lines, line)
+}
+
+// prefixes returns what goes in front of the first and following lines of
+// the current block: quote bars, list indentation and the item's bullet.
+func (r *renderer) prefixes() (string, string) {
+	base := strings.Repeat("│ ", r.quoteDepth)
+	if len(r.lists) > 0 {
+		base += strings.Repeat("  ", len(r.lists)-1)
+	}
+	if r.bullet == "" {
+		if len(r.lists) > 0 {
+			base += "  "
+		}
+		return base, base
+	}
+	return base + r.bullet, base + strings.Repeat(" ", displayWidth(r.bullet))
+}
+
+// endLine finishes the current line but stays in the same block.
+func (r *renderer) endLine() {
+	if len(r.words) == 0 {
+		r.emit(strings.TrimRight(r.continuationPrefix(), " "))
+		return
+	}
+	r.endBlock()
+}
+
+func (r *renderer) continuationPrefix() string {
+	_, rest := r.prefixes()
+	return rest
+}
+
+func (r *renderer) endBlock() {
+	r.pendingSpace = false
+	if len(r.words) == 0 {
+		return
+	}
+	first, rest := r.prefixes()
+	for _, line := range r.wrap(r.words, first, rest) {
+		r.emit(line)
+	}
+	r.words = nil
+	// the bullet only goes on the item's first line
+	r.bullet = ""
+}
+
+func (r *renderer) endPre() {
+	text := strings.Trim(r.preText.String(), "\n")
+	r.preText.Reset()
+	if text == "" {
+		return
+	}
+	first, rest := r.prefixes()
+	for i, line := range strings.Split(text, "\n") {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		r.emit(prefix + strings.TrimRight(line, " \t\r"))
+	}
+	r.bullet = ""
+}
+
+func (r *renderer) wrap(words []word, first, rest string) []string {
+	var lines []string
+	var line strings.Builder
+	prefix := first
+	lineWidth := 0
+
+	flush := func() {
+		lines = append(lines, prefix+line.String())
+		line.Reset()
+		lineWidth = 0
+		prefix = rest
+	}
+
+	for _, w := range words {
+		chunks := []string{w.text}
+		if r.opts.Width > 0 {
+			chunks = splitWide(w.text, r.opts.Width-displayWidth(rest))
+		}
+		for i, chunk := range chunks {
+			width := displayWidth(chunk)
+			sep := 0
+			if lineWidth > 0 && w.space && i == 0 {
+				sep = 1
+			}
+			if r.opts.Width > 0 && lineWidth > 0 && displayWidth(prefix)+lineWidth+sep+width > r.opts.Width {
+				flush()
+				sep = 0
+			}
+			if sep == 1 {
+				line.WriteByte(' ')
+			}
+			line.WriteString(r.styled(chunk, w.style))
+			lineWidth += sep + width
+		}
+	}
+	if lineWidth > 0 {
+		flush()
+	}
+	return lines
+}
+
+func (r *renderer) styled(text string, s style) string {
+	if !r.opts.Color || s == 0 {
+		return text
+	}
+	var codes []string
+	if s&styleBold != 0 {
+		codes = append(codes, "1")
+	}
+	if s&styleItalic != 0 {
+		codes = append(codes, "3")
+	}
+	if s&styleUnderline != 0 {
+		codes = append(codes, "4")
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m" + text + "\x1b[0m"
+}
+
+// splitWide breaks a word that can't fit on a line of the given width.
+func splitWide(text string, width int) []string {
+	if width < 1 || displayWidth(text) <= width {
+		return []string{text}
+	}
+	var chunks []string
+	var chunk strings.Builder
+	chunkWidth := 0
+	for _, r := range text {
+		if chunkWidth+runeWidth(r) > width {
+			chunks = append(chunks, chunk.String())
+			chunk.Reset()
+			chunkWidth = 0
+		}
+		chunk.WriteRune(r)
+		chunkWidth += runeWidth(r)
+	}
+	return append(chunks, chunk.String())
+}
+
+func displayWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth counts East Asian wide characters as two columns.
+func runeWidth(r rune) int {
+	switch {
+	case r >= 0x1100 && r <= 0x115F,
+		r >= 0x2E80 && r <= 0xA4CF,
+		r >= 0xAC00 && r <= 0xD7A3,
+		r >= 0xF900 && r <= 0xFAFF,
+		r >= 0xFE30 && r <= 0xFE4F,
+		r >= 0xFF00 && r <= 0xFF60,
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x20000 && r <= 0x3FFFD:
+		return 2
+	case unicode.Is(unicode.Mn, r):
+		return 0
+	}
+	return 1
+}
+
+func isHeading(a atom.Atom) bool {
+	switch a {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		return true
+	}
+	return false
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
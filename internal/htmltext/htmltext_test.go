@@ -0,0 +1,156 @@
+package htmltext
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  Options
+		want  string
+	}{
+		{
+			name:  "plain text",
+			input: "hello   world",
+			want:  "hello world",
+		},
+		{
+			name:  "paragraphs",
+			input: "<p>one</p><p>two</p>",
+			want:  "one\n\ntwo",
+		},
+		{
+			name:  "line break",
+			input: "one<br>two",
+			want:  "one\ntwo",
+		},
+		{
+			name:  "entities decoded",
+			input: "Tom &amp; Jerry &lt;3 &quot;cats&quot; &#8212; caf&eacute;&nbsp;au&#x20;lait",
+			want:  "Tom & Jerry <3 \"cats\" — café au lait",
+		},
+		{
+			name:  "unordered list",
+			input: "<ul><li>one</li><li>two</li></ul>",
+			want:  "• one\n• two",
+		},
+		{
+			name:  "ordered list",
+			input: "<ol><li>one</li><li>two</li></ol>",
+			want:  "1. one\n2. two",
+		},
+		{
+			name:  "ordered list with start",
+			input: `<ol start="9"><li>nine</li><li>ten</li></ol>`,
+			want:  "9. nine\n10. ten",
+		},
+		{
+			name:  "nested list",
+			input: "<ul><li>one<ul><li>inner</li></ul></li><li>two</li></ul>",
+			want:  "• one\n  • inner\n• two",
+		},
+		{
+			name:  "list between paragraphs",
+			input: "<p>before</p><ul><li>item</li></ul><p>after</p>",
+			want:  "before\n\n• item\n\nafter",
+		},
+		{
+			name:  "wrapped list item indented",
+			input: "<ul><li>one two three</li></ul>",
+			opts:  Options{Width: 9},
+			want:  "• one two\n  three",
+		},
+		{
+			name:  "link as footnote",
+			input: `see <a href="https://go.dev/">the site</a> now`,
+			want:  "see the site[1] now\n\n[1] https://go.dev/",
+		},
+		{
+			name:  "links numbered in order",
+			input: `<a href="https://a.example/">a</a> and <a href="https://b.example/">b</a>`,
+			want:  "a[1] and b[2]\n\n[1] https://a.example/\n[2] https://b.example/",
+		},
+		{
+			name:  "fragment and javascript links not listed",
+			input: `<a href="#top">top</a> <a href="JavaScript:alert(1)">x</a>`,
+			want:  "top x",
+		},
+		{
+			name:  "link entities decoded",
+			input: `<a href="https://a.example/?x=1&amp;y=2">q</a>`,
+			want:  "q[1]\n\n[1] https://a.example/?x=1&y=2",
+		},
+		{
+			name:  "pre keeps whitespace",
+			input: "<pre>func main() {\n\tfmt.Println(\"hi\")\n}</pre>",
+			want:  "func main() {\n\tfmt.Println(\"hi\")\n}",
+		},
+		{
+			name:  "pre not wrapped",
+			input: "<pre>one two three four</pre>",
+			opts:  Options{Width: 5},
+			want:  "one two three four",
+		},
+		{
+			name:  "pre entities decoded",
+			input: "<pre>a &lt; b &amp;&amp; c</pre>",
+			want:  "a < b && c",
+		},
+		{
+			name:  "pre between paragraphs",
+			input: "<p>code:</p><pre>\nx := 1\n</pre><p>done</p>",
+			want:  "code:\n\nx := 1\n\ndone",
+		},
+		{
+			name:  "blockquote",
+			input: "<blockquote>quoted</blockquote>",
+			want:  "│ quoted",
+		},
+		{
+			name:  "script dropped",
+			input: "<p>a</p><script>alert(1)</script>",
+			want:  "a",
+		},
+		{
+			name:  "image alt",
+			input: `<img src="x.png" alt="a cat">`,
+			want:  "[image: a cat]",
+		},
+		{
+			name:  "wrapping",
+			input: "one two three four",
+			opts:  Options{Width: 9},
+			want:  "one two\nthree\nfour",
+		},
+		{
+			name:  "color",
+			input: "<b>bold</b> plain",
+			opts:  Options{Color: true},
+			want:  "\x1b[1mbold\x1b[0m plain",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.input, tt.opts); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLine(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Go &amp; Rust", "Go & Rust"},
+		{"<b>Bold</b>\n title", "Bold title"},
+		{"<p>one</p><p>two</p>", "one two"},
+		{`<a href="https://go.dev/">link</a>`, "link[1] [1] https://go.dev/"},
+	}
+	for _, tt := range tests {
+		if got := Line(tt.input); got != tt.want {
+			t.Errorf("Line(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
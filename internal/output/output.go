@@ -0,0 +1,142 @@
+package output
+
+import (
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Formats lists the supported values for --output.
+var Formats = []string{"json", "csv", "tsv", "table"}
+
+// Table is a list of records sharing the same columns. Values may be any
+// type; database types such as sql.NullString and uuid.UUID are unwrapped.
+type Table struct {
+	Columns []string
+	Rows    [][]any
+}
+
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Write renders t in the given format.
+func Write(w io.Writer, format string, t Table) error {
+	switch format {
+	case "json":
+		return writeJSON(w, t)
+	case "csv":
+		return writeDelimited(w, ',', t)
+	case "tsv":
+		return writeDelimited(w, '\t', t)
+	case "table":
+		return writeTable(w, t)
+	default:
+		return fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// writeJSON writes an array of objects whose keys follow the column order.
+func writeJSON(w io.Writer, t Table) error {
+	var b strings.Builder
+	b.WriteString("[")
+	for i, row := range t.Rows {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  {")
+		for j, column := range t.Columns {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			key, err := json.Marshal(column)
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(plain(row[j]))
+			if err != nil {
+				return err
+			}
+			b.Write(key)
+			b.WriteString(": ")
+			b.Write(value)
+		}
+		b.WriteString("}")
+	}
+	if len(t.Rows) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeDelimited(w io.Writer, comma rune, t Table) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	if err := writer.Write(t.Columns); err != nil {
+		return err
+	}
+	for _, row := range t.Rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = text(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeTable(w io.Writer, t Table) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(t.Columns, "\t")))
+	for _, row := range t.Rows {
+		cells := make([]string, len(row))
+		for i, value := range row {
+			// keep multi-line values on their row
+			cells[i] = strings.Join(strings.Fields(text(value)), " ")
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// plain unwraps nullable and database-specific types to values that marshal
+// naturally; NULL becomes nil.
+func plain(value any) any {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return nil
+		}
+		value = v
+	}
+	if t, ok := value.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
+
+func text(value any) string {
+	value = plain(value)
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,160 @@
+package output
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteDelimited(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		row    []any
+		want   string
+	}{
+		{
+			name:   "plain csv",
+			format: "csv",
+			row:    []any{"Go blog", 42},
+			want:   "name,count\nGo blog,42\n",
+		},
+		{
+			name:   "csv comma quoted",
+			format: "csv",
+			row:    []any{"Tom, Jerry", 1},
+			want:   "name,count\n\"Tom, Jerry\",1\n",
+		},
+		{
+			name:   "csv quotes doubled",
+			format: "csv",
+			row:    []any{`say "hi"`, 1},
+			want:   "name,count\n\"say \"\"hi\"\"\",1\n",
+		},
+		{
+			name:   "csv newline quoted",
+			format: "csv",
+			row:    []any{"one\ntwo", 1},
+			want:   "name,count\n\"one\ntwo\",1\n",
+		},
+		{
+			name:   "csv tab left alone",
+			format: "csv",
+			row:    []any{"a\tb", 1},
+			want:   "name,count\na\tb,1\n",
+		},
+		{
+			name:   "plain tsv",
+			format: "tsv",
+			row:    []any{"Go blog", 42},
+			want:   "name\tcount\nGo blog\t42\n",
+		},
+		{
+			name:   "tsv tab quoted",
+			format: "tsv",
+			row:    []any{"a\tb", 1},
+			want:   "name\tcount\n\"a\tb\"\t1\n",
+		},
+		{
+			name:   "tsv comma left alone",
+			format: "tsv",
+			row:    []any{"Tom, Jerry", 1},
+			want:   "name\tcount\nTom, Jerry\t1\n",
+		},
+		{
+			name:   "tsv quotes doubled",
+			format: "tsv",
+			row:    []any{`say "hi"`, 1},
+			want:   "name\tcount\n\"say \"\"hi\"\"\"\t1\n",
+		},
+		{
+			name:   "null is empty",
+			format: "csv",
+			row:    []any{sql.NullString{}, sql.NullInt32{Int32: 3, Valid: true}},
+			want:   "name,count\n,3\n",
+		},
+		{
+			name:   "time as RFC 3339",
+			format: "tsv",
+			row:    []any{time.Date(2024, time.March, 6, 10, 30, 0, 0, time.UTC), 1},
+			want:   "name\tcount\n2024-03-06T10:30:00Z\t1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			table := Table{Columns: []string{"name", "count"}, Rows: [][]any{tt.row}}
+			if err := Write(&b, tt.format, table); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("Write()\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	table := Table{
+		Columns: []string{"name", "url"},
+		Rows: [][]any{
+			{"Go blog", sql.NullString{String: "https://go.dev/blog/feed.atom", Valid: true}},
+			{`"quoted"`, sql.NullString{}},
+		},
+	}
+	want := "[\n" +
+		`  {"name": "Go blog", "url": "https://go.dev/blog/feed.atom"},` + "\n" +
+		`  {"name": "\"quoted\"", "url": null}` + "\n" +
+		"]\n"
+	var b strings.Builder
+	if err := Write(&b, "json", table); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("Write()\n got %q\nwant %q", got, want)
+	}
+
+	b.Reset()
+	if err := Write(&b, "json", Table{Columns: table.Columns}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if got := b.String(); got != "[]\n" {
+		t.Errorf("Write() with no rows = %q, want %q", got, "[]\n")
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	table := Table{
+		Columns: []string{"name", "count"},
+		Rows: [][]any{
+			{"Go blog", 42},
+			{"two\nlines", 7},
+		},
+	}
+	want := "NAME       COUNT\n" +
+		"Go blog    42\n" +
+		"two lines  7\n"
+	var b strings.Builder
+	if err := Write(&b, "table", table); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("Write()\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	var b strings.Builder
+	if err := Write(&b, "xml", Table{}); err == nil {
+		t.Error("Write() with an unknown format succeeded")
+	}
+	if ValidFormat("xml") {
+		t.Error(`ValidFormat("xml") = true`)
+	}
+	for _, format := range Formats {
+		if !ValidFormat(format) {
+			t.Errorf("ValidFormat(%q) = false", format)
+		}
+	}
+}
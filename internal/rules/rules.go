@@ -0,0 +1,118 @@
+// Package rules matches posts against the patterns users set up to act on
+// incoming posts.
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Fields a rule can look at. Any looks at all of them.
+const (
+	Any      = "any"
+	Title    = "title"
+	Content  = "content"
+	Author   = "author"
+	Category = "category"
+)
+
+// Actions a rule can take on the posts it matches.
+const (
+	Hide   = "hide"
+	Read   = "read"
+	Star   = "star"
+	Tag    = "tag"
+	Notify = "notify"
+)
+
+var (
+	Fields  = []string{Any, Title, Content, Author, Category}
+	Actions = []string{Hide, Read, Star, Tag, Notify}
+)
+
+// Post is what rules are matched against.
+type Post struct {
+	Title      string
+	Content    string
+	Author     string
+	Categories []string
+}
+
+// Matcher is a compiled rule pattern.
+type Matcher struct {
+	field   string
+	keyword string
+	re      *regexp.Regexp
+}
+
+func ValidField(field string) bool {
+	return contains(Fields, field)
+}
+
+func ValidAction(action string) bool {
+	return contains(Actions, action)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Compile makes a matcher for pattern on field. Keywords match anywhere in
+// the field, ignoring case; regular expressions use Go's syntax and are case
+// sensitive unless they start with (?i).
+func Compile(field, pattern string, regex bool) (Matcher, error) {
+	if !ValidField(field) {
+		return Matcher{}, fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(Fields, ", "))
+	}
+	if pattern == "" {
+		return Matcher{}, fmt.Errorf("empty pattern")
+	}
+	m := Matcher{field: field}
+	if !regex {
+		m.keyword = strings.ToLower(pattern)
+		return m, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Matcher{}, err
+	}
+	m.re = re
+	return m, nil
+}
+
+// Match reports whether the post's field matches. A post matches on its
+// categories if any one of them does.
+func (m Matcher) Match(post Post) bool {
+	var values []string
+	if m.field == Any || m.field == Title {
+		values = append(values, post.Title)
+	}
+	if m.field == Any || m.field == Content {
+		values = append(values, post.Content)
+	}
+	if m.field == Any || m.field == Author {
+		values = append(values, post.Author)
+	}
+	if m.field == Any || m.field == Category {
+		values = append(values, post.Categories...)
+	}
+	for _, value := range values {
+		if m.matchValue(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m Matcher) matchValue(value string) bool {
+	if m.re != nil {
+		return m.re.MatchString(value)
+	}
+	return strings.Contains(strings.ToLower(value), m.keyword)
+}
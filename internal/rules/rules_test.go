@@ -0,0 +1,109 @@
+package rules
+
+import "testing"
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		pattern string
+		regex   bool
+	}{
+		{"unknown field", "summary", "go", false},
+		{"empty keyword", Any, "", false},
+		{"empty regex", Title, "", true},
+		{"invalid regex", Title, "go(", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile(tt.field, tt.pattern, tt.regex); err == nil {
+				t.Errorf("Compile(%q, %q, %t) succeeded, want an error", tt.field, tt.pattern, tt.regex)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	post := Post{
+		Title:      "Go 1.23 is released",
+		Content:    "Range over functions and a new iter package.",
+		Author:     "The Go Team",
+		Categories: []string{"Releases", "golang"},
+	}
+	tests := []struct {
+		name    string
+		field   string
+		pattern string
+		regex   bool
+		want    bool
+	}{
+		{"keyword in title", Title, "released", false, true},
+		{"keyword ignores case", Title, "RELEASED", false, true},
+		{"keyword not in title", Title, "iter", false, false},
+		{"keyword in content", Content, "iter package", false, true},
+		{"keyword in author", Author, "go team", false, true},
+		{"keyword in one category", Category, "golang", false, true},
+		{"keyword part of a category", Category, "release", false, true},
+		{"keyword in no category", Category, "rust", false, false},
+		{"any field, title", Any, "1.23", false, true},
+		{"any field, content", Any, "range over", false, true},
+		{"any field, author", Any, "team", false, true},
+		{"any field, category", Any, "golang", false, true},
+		{"any field, nowhere", Any, "python", false, false},
+		{"regex matches", Title, `^Go \d+\.\d+`, true, true},
+		{"regex is case sensitive", Title, `^go`, true, false},
+		{"regex with (?i)", Title, `(?i)^go`, true, true},
+		{"regex anchors per category", Category, `^golang$`, true, true},
+		{"regex anchors don't span fields", Any, `released.*Range`, true, false},
+		{"keyword is not a regex", Title, `1.23 is`, false, true},
+		{"regex metacharacters in a keyword", Title, `Go.*released`, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Compile(tt.field, tt.pattern, tt.regex)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if got := m.Match(post); got != tt.want {
+				t.Errorf("Match() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchEmptyPost(t *testing.T) {
+	m, err := Compile(Any, ".*", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an empty title is still a value a regex can match
+	if !m.Match(Post{}) {
+		t.Error("Match(Post{}) = false, want true")
+	}
+	m, err = Compile(Category, ".*", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Match(Post{Title: "x"}) {
+		t.Error("category rule matched a post without categories")
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, field := range Fields {
+		if !ValidField(field) {
+			t.Errorf("ValidField(%q) = false", field)
+		}
+	}
+	for _, action := range Actions {
+		if !ValidAction(action) {
+			t.Errorf("ValidAction(%q) = false", action)
+		}
+	}
+	if ValidField("summary") || ValidField("") {
+		t.Error("ValidField accepted an unknown field")
+	}
+	if ValidAction("delete") || ValidAction("") {
+		t.Error("ValidAction accepted an unknown action")
+	}
+}
@@ -0,0 +1,249 @@
+// Package sanitize cleans feed-supplied HTML so it can be stored and shown
+// safely.
+package sanitize
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedAttrs lists the elements we keep and the attributes each may carry.
+// Any other element is unwrapped: dropped, but its children are kept.
+var allowedAttrs = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Abbr:       {"title"},
+	atom.B:          nil,
+	atom.Blockquote: {"cite"},
+	atom.Br:         nil,
+	atom.Caption:    nil,
+	atom.Cite:       nil,
+	atom.Code:       nil,
+	atom.Dd:         nil,
+	atom.Del:        nil,
+	atom.Details:    nil,
+	atom.Div:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt", "title", "width", "height"},
+	atom.Ins:        nil,
+	atom.Kbd:        nil,
+	atom.Li:         nil,
+	atom.Mark:       nil,
+	atom.Ol:         {"start"},
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.Q:          {"cite"},
+	atom.S:          nil,
+	atom.Small:      nil,
+	atom.Span:       nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Summary:    nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"colspan", "rowspan"},
+	atom.Tfoot:      nil,
+	atom.Th:         {"colspan", "rowspan"},
+	atom.Thead:      nil,
+	atom.Time:       {"datetime"},
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// dropped elements are removed together with everything inside them.
+var dropped = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Frame:    true,
+	atom.Frameset: true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Applet:   true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Form:     true,
+	atom.Input:    true,
+	atom.Button:   true,
+	atom.Select:   true,
+	atom.Textarea: true,
+	atom.Svg:      true,
+	atom.Math:     true,
+	atom.Head:     true,
+	atom.Title:    true,
+	atom.Meta:     true,
+	atom.Link:     true,
+	atom.Base:     true,
+}
+
+// trackerHosts serve the invisible images feeds use to count readers.
+var trackerHosts = []string{
+	"feeds.feedburner.com",
+	"feedproxy.google.com",
+	"pixel.wp.com",
+	"stats.wordpress.com",
+	"pixel.quantserve.com",
+	"www.google-analytics.com",
+	"ad.doubleclick.net",
+}
+
+// HTML returns fragment with only allow-listed elements and attributes.
+// Relative links and image sources are resolved against base; URLs that
+// can't be made absolute, or use schemes other than http(s) and mailto,
+// are removed, as are tracking pixels.
+func HTML(fragment string, base *url.URL) string {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		return html.EscapeString(fragment)
+	}
+
+	var b strings.Builder
+	for _, n := range nodes {
+		for _, clean := range sanitizeNode(n, base) {
+			if err := html.Render(&b, clean); err != nil {
+				return ""
+			}
+		}
+	}
+	return b.String()
+}
+
+// sanitizeNode returns the nodes that replace n: n itself when it is
+// allowed, its children when it is unwrapped, or nothing.
+func sanitizeNode(n *html.Node, base *url.URL) []*html.Node {
+	switch n.Type {
+	case html.TextNode:
+		return []*html.Node{n}
+	case html.ElementNode:
+	default:
+		// comments, doctypes
+		return nil
+	}
+	if dropped[n.DataAtom] {
+		return nil
+	}
+
+	var children []*html.Node
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		n.RemoveChild(child)
+		children = append(children, sanitizeNode(child, base)...)
+		child = next
+	}
+
+	allowed, ok := allowedAttrs[n.DataAtom]
+	if !ok || n.Namespace != "" {
+		return children
+	}
+
+	n.Attr = cleanAttrs(n, allowed, base)
+	switch n.DataAtom {
+	case atom.Img:
+		if attr(n, "src") == "" || isTrackingPixel(n) {
+			return nil
+		}
+	case atom.A:
+		if attr(n, "href") != "" {
+			n.Attr = append(n.Attr, html.Attribute{Key: "rel", Val: "nofollow noopener noreferrer"})
+		}
+	}
+	for _, child := range children {
+		n.AppendChild(child)
+	}
+	return []*html.Node{n}
+}
+
+func cleanAttrs(n *html.Node, allowed []string, base *url.URL) []html.Attribute {
+	var attrs []html.Attribute
+	for _, a := range n.Attr {
+		if a.Namespace != "" || !contains(allowed, a.Key) {
+			continue
+		}
+		switch a.Key {
+		case "href", "src", "cite":
+			resolved, ok := resolveURL(a.Val, base, a.Key == "href")
+			if !ok {
+				continue
+			}
+			a.Val = resolved
+		}
+		attrs = append(attrs, a)
+	}
+	return attrs
+}
+
+func resolveURL(raw string, base *url.URL, allowMailto bool) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", false
+	}
+	if !u.IsAbs() {
+		if base == nil || !base.IsAbs() {
+			return "", false
+		}
+		u = base.ResolveReference(u)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.String(), true
+	case "mailto":
+		return u.String(), allowMailto
+	}
+	return "", false
+}
+
+func isTrackingPixel(n *html.Node) bool {
+	width, widthErr := strconv.Atoi(strings.TrimSuffix(attr(n, "width"), "px"))
+	height, heightErr := strconv.Atoi(strings.TrimSuffix(attr(n, "height"), "px"))
+	if widthErr == nil && heightErr == nil && width <= 1 && height <= 1 {
+		return true
+	}
+
+	src, err := url.Parse(attr(n, "src"))
+	if err != nil {
+		return true
+	}
+	host := strings.ToLower(src.Hostname())
+	for _, tracker := range trackerHosts {
+		if host == tracker {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+package sanitize
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	base, err := url.Parse("https://example.com/posts/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "plain text",
+			input: "Tom & Jerry <3",
+			want:  "Tom &amp; Jerry &lt;3",
+		},
+		{
+			name:  "allowed markup kept",
+			input: `<p>Some <b>bold</b> and <em>emphasis</em></p>`,
+			want:  `<p>Some <b>bold</b> and <em>emphasis</em></p>`,
+		},
+		{
+			name:  "unknown elements unwrapped",
+			input: `<section><custom-tag>kept</custom-tag></section>`,
+			want:  `kept`,
+		},
+		{
+			name:  "script dropped",
+			input: `<p>before</p><script>alert(1)</script><p>after</p>`,
+			want:  `<p>before</p><p>after</p>`,
+		},
+		{
+			name:  "uppercase script dropped",
+			input: `<SCRIPT SRC="https://evil.example/x.js"></SCRIPT>ok`,
+			want:  `ok`,
+		},
+		{
+			name:  "style dropped",
+			input: `<style>body { display: none }</style><p>text</p>`,
+			want:  `<p>text</p>`,
+		},
+		{
+			name:  "event handler attributes dropped",
+			input: `<p onclick="alert(1)" onmouseover="alert(2)">hi</p><img src="/a.png" onerror="alert(3)">`,
+			want:  `<p>hi</p><img src="https://example.com/a.png"/>`,
+		},
+		{
+			name:  "javascript href",
+			input: `<a href="javascript:alert(1)">x</a>`,
+			want:  `<a>x</a>`,
+		},
+		{
+			name:  "mixed case javascript href",
+			input: `<a href="JaVaScRiPt:alert(1)">x</a>`,
+			want:  `<a>x</a>`,
+		},
+		{
+			name:  "javascript href with leading space",
+			input: `<a href="  javascript:alert(1)">x</a>`,
+			want:  `<a>x</a>`,
+		},
+		{
+			name:  "entity-encoded javascript href",
+			input: `<a href="&#106;&#97;&#118;&#97;&#115;&#99;&#114;&#105;&#112;&#116;&#58;alert(1)">x</a>`,
+			want:  `<a>x</a>`,
+		},
+		{
+			name:  "hex entity-encoded javascript href",
+			input: `<a href="&#x6A;avascript&#x3A;alert(1)">x</a>`,
+			want:  `<a>x</a>`,
+		},
+		{
+			name:  "named entity colon in javascript href",
+			input: `<a href="javascript&colon;alert(1)">x</a>`,
+			want:  `<a>x</a>`,
+		},
+		{
+			name:  "tab inside javascript href",
+			input: `<a href="java&#x09;script:alert(1)">x</a>`,
+			want:  `<a>x</a>`,
+		},
+		{
+			name:  "data href",
+			input: `<a href="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">x</a>`,
+			want:  `<a>x</a>`,
+		},
+		{
+			name:  "data image source",
+			input: `<img src="DATA:image/svg+xml,&lt;svg onload=alert(1)&gt;">`,
+			want:  ``,
+		},
+		{
+			name:  "vbscript href",
+			input: `<a href="VBScript:MsgBox(1)">x</a>`,
+			want:  `<a>x</a>`,
+		},
+		{
+			name:  "mailto href kept",
+			input: `<a href="mailto:someone@example.com">mail</a>`,
+			want:  `<a href="mailto:someone@example.com" rel="nofollow noopener noreferrer">mail</a>`,
+		},
+		{
+			name:  "mailto image source dropped",
+			input: `<img src="mailto:someone@example.com">`,
+			want:  ``,
+		},
+		{
+			name:  "svg dropped",
+			input: `<svg onload="alert(1)"><script>alert(2)</script><circle r="1"/></svg>ok`,
+			want:  `ok`,
+		},
+		{
+			name:  "math dropped",
+			input: `<math><mi xlink:href="javascript:alert(1)">x</mi></math>ok`,
+			want:  `ok`,
+		},
+		{
+			name:  "iframe dropped",
+			input: `<iframe src="https://evil.example/"><p>fallback</p></iframe>ok`,
+			want:  `ok`,
+		},
+		{
+			name:  "form dropped",
+			input: `<form action="https://evil.example/"><input name="password"><button>Go</button></form>ok`,
+			want:  `ok`,
+		},
+		{
+			name:  "object and embed dropped",
+			input: `<object data="x.swf"><embed src="x.swf"></object>ok`,
+			want:  `ok`,
+		},
+		{
+			name:  "meta and base dropped",
+			input: `<base href="https://evil.example/"><meta http-equiv="refresh" content="0;url=https://evil.example/">ok`,
+			want:  `ok`,
+		},
+		{
+			name:  "srcset dropped",
+			input: `<img src="https://example.com/a.png" srcset="javascript:alert(1) 1x, https://evil.example/b.png 2x" alt="a">`,
+			want:  `<img src="https://example.com/a.png" alt="a"/>`,
+		},
+		{
+			name:  "style attribute dropped",
+			input: `<p style="background: url(javascript:alert(1))">x</p><span style="position:fixed;top:0">y</span>`,
+			want:  `<p>x</p><span>y</span>`,
+		},
+		{
+			name:  "id and class dropped",
+			input: `<div id="login" class="overlay">x</div>`,
+			want:  `<div>x</div>`,
+		},
+		{
+			name:  "unclosed tags closed",
+			input: `<p>one<p>two <b>bold`,
+			want:  `<p>one</p><p>two <b>bold</b></p>`,
+		},
+		{
+			name:  "unclosed script",
+			input: `ok<script>alert(1)`,
+			want:  `ok`,
+		},
+		{
+			name:  "unterminated attribute",
+			input: `<a href="https://example.com/ onclick=alert(1)>x`,
+			want:  ``,
+		},
+		{
+			name:  "comments dropped",
+			input: `a<!-- <script>alert(1)</script> -->b`,
+			want:  `ab`,
+		},
+		{
+			name:  "relative href resolved",
+			input: `<a href="../about">about</a>`,
+			want:  `<a href="https://example.com/about" rel="nofollow noopener noreferrer">about</a>`,
+		},
+		{
+			name:  "root-relative image resolved",
+			input: `<img src="/img/cat.jpg" alt="cat">`,
+			want:  `<img src="https://example.com/img/cat.jpg" alt="cat"/>`,
+		},
+		{
+			name:  "sibling image resolved",
+			input: `<img src="cat.jpg">`,
+			want:  `<img src="https://example.com/posts/cat.jpg"/>`,
+		},
+		{
+			name:  "protocol-relative href resolved",
+			input: `<a href="//cdn.example.net/x">x</a>`,
+			want:  `<a href="https://cdn.example.net/x" rel="nofollow noopener noreferrer">x</a>`,
+		},
+		{
+			name:  "fragment href resolved",
+			input: `<a href="#notes">notes</a>`,
+			want:  `<a href="https://example.com/posts/1#notes" rel="nofollow noopener noreferrer">notes</a>`,
+		},
+		{
+			name:  "blockquote cite resolved",
+			input: `<blockquote cite="/source">q</blockquote>`,
+			want:  `<blockquote cite="https://example.com/source">q</blockquote>`,
+		},
+		{
+			name:  "feed-supplied rel replaced",
+			input: `<a href="https://example.org/" rel="opener" target="_blank">x</a>`,
+			want:  `<a href="https://example.org/" rel="nofollow noopener noreferrer">x</a>`,
+		},
+		{
+			name:  "tracking pixel by size",
+			input: `<p>x<img src="https://example.org/p.gif" width="1" height="1"></p>`,
+			want:  `<p>x</p>`,
+		},
+		{
+			name:  "tracking pixel by host",
+			input: `<img src="https://feeds.feedburner.com/~r/x/~4/abc">`,
+			want:  ``,
+		},
+		{
+			name:  "image without a source",
+			input: `<img alt="nothing">`,
+			want:  ``,
+		},
+		{
+			name:  "attribute values escaped",
+			input: `<img src="https://example.com/a.png" alt="&quot;&gt;&lt;script&gt;alert(1)&lt;/script&gt;">`,
+			want:  `<img src="https://example.com/a.png" alt="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;"/>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.input, base); got != tt.want {
+				t.Errorf("HTML(%q)\n got %q\nwant %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLWithoutBase(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "relative href dropped",
+			input: `<a href="/about">about</a>`,
+			want:  `<a>about</a>`,
+		},
+		{
+			name:  "relative image dropped",
+			input: `<img src="cat.jpg">`,
+			want:  ``,
+		},
+		{
+			name:  "absolute href kept",
+			input: `<a href="https://example.com/">home</a>`,
+			want:  `<a href="https://example.com/" rel="nofollow noopener noreferrer">home</a>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.input, nil); got != tt.want {
+				t.Errorf("HTML(%q)\n got %q\nwant %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
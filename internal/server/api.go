@@ -0,0 +1,608 @@
+package server
+
+import (
+	"database/sql"
+	_ "embed"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/auth"
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/jamistoso/gator/internal/htmltext"
+	"github.com/jamistoso/gator/internal/sanitize"
+)
+
+// APIPrefix is where version 1 of the REST API lives.
+const APIPrefix = "/api/v1"
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+var postSortOrders = []string{"published", "oldest", "fetched", "feed"}
+
+func (s *Server) apiRoutes() {
+	s.mux.HandleFunc("GET "+APIPrefix+"/openapi.yaml", s.handleOpenAPI)
+
+	s.mux.HandleFunc("GET "+APIPrefix+"/users", s.requireToken(s.handleListUsers))
+	s.mux.HandleFunc("POST "+APIPrefix+"/users", s.requireToken(s.handleCreateUser))
+	s.mux.HandleFunc("GET "+APIPrefix+"/users/{user}", s.requireToken(s.handleGetUser))
+
+	s.mux.HandleFunc("GET "+APIPrefix+"/feeds", s.requireToken(s.handleListFeeds))
+	s.mux.HandleFunc("GET "+APIPrefix+"/feeds/{feed}", s.requireToken(s.handleGetFeed))
+	s.mux.HandleFunc("POST "+APIPrefix+"/users/{user}/feeds", s.requireToken(s.handleCreateFeed))
+
+	s.mux.HandleFunc("GET "+APIPrefix+"/users/{user}/follows", s.requireToken(s.handleListFollows))
+	s.mux.HandleFunc("POST "+APIPrefix+"/users/{user}/follows", s.requireToken(s.handleCreateFollow))
+	s.mux.HandleFunc("DELETE "+APIPrefix+"/users/{user}/follows/{feed}", s.requireToken(s.handleDeleteFollow))
+
+	s.mux.HandleFunc("GET "+APIPrefix+"/users/{user}/posts", s.requireToken(s.handleListPosts))
+	s.mux.HandleFunc("GET "+APIPrefix+"/users/{user}/posts/{post}", s.requireToken(s.handleGetPost))
+	s.mux.HandleFunc("PUT "+APIPrefix+"/users/{user}/posts/{post}/read", s.requireToken(s.handleSetRead(true)))
+	s.mux.HandleFunc("DELETE "+APIPrefix+"/users/{user}/posts/{post}/read", s.requireToken(s.handleSetRead(false)))
+	s.mux.HandleFunc("PUT "+APIPrefix+"/users/{user}/posts/{post}/star", s.requireToken(s.handleSetStarred(true)))
+	s.mux.HandleFunc("DELETE "+APIPrefix+"/users/{user}/posts/{post}/star", s.requireToken(s.handleSetStarred(false)))
+}
+
+type apiUser struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type apiFeed struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	NextFetchAt   *time.Time `json:"next_fetch_at"`
+	// DeactivatedAt is set once the feed answered 410 Gone.
+	DeactivatedAt *time.Time `json:"deactivated_at"`
+}
+
+type apiFollow struct {
+	FeedID     uuid.UUID `json:"feed_id"`
+	FeedName   string    `json:"feed_name"`
+	FeedURL    string    `json:"feed_url"`
+	FollowedAt time.Time `json:"followed_at"`
+	Gone       bool      `json:"gone"`
+}
+
+type apiPost struct {
+	ID          uuid.UUID  `json:"id"`
+	FeedID      uuid.UUID  `json:"feed_id"`
+	FeedName    string     `json:"feed_name"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	PublishedAt *time.Time `json:"published_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ContentHTML string     `json:"content_html"`
+	ContentText string     `json:"content_text"`
+	Read        bool       `json:"read"`
+	Starred     bool       `json:"starred"`
+}
+
+func userJSON(user database.User) apiUser {
+	return apiUser{
+		ID:        user.ID,
+		Name:      user.Name,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+}
+
+func feedJSON(feed database.Feed) apiFeed {
+	return apiFeed{
+		ID:            feed.ID,
+		Name:          feed.Name,
+		URL:           feed.Url,
+		CreatedAt:     feed.CreatedAt,
+		UpdatedAt:     feed.UpdatedAt,
+		LastFetchedAt: nullTime(feed.LastFetchedAt),
+		NextFetchAt:   nullTime(feed.NextFetchAt),
+		DeactivatedAt: nullTime(feed.DeactivatedAt),
+	}
+}
+
+func postJSON(post database.GetPostsForUserRow) apiPost {
+	contentHTML, contentText := postContent(post.Description, post.ContentHtml, post.ContentText, post.Url)
+	return apiPost{
+		ID:          post.ID,
+		FeedID:      post.FeedID.UUID,
+		FeedName:    post.FeedName,
+		Title:       htmltext.Line(post.Title.String),
+		URL:         post.Url,
+		PublishedAt: nullTime(post.PublishedAt),
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+		ContentHTML: contentHTML,
+		ContentText: contentText,
+		Read:        post.IsRead,
+		Starred:     post.IsStarred,
+	}
+}
+
+// postContent returns a post's sanitized HTML and its text. Posts stored
+// before sanitizing was added only have a description, which is cleaned
+// here instead.
+func postContent(description, contentHTML, contentText sql.NullString, link string) (string, string) {
+	if contentHTML.Valid {
+		return contentHTML.String, contentText.String
+	}
+	base, _ := url.Parse(link)
+	clean := sanitize.HTML(description.String, base)
+	return clean, htmltext.Render(clean, htmltext.Options{})
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
+
+// pathUser loads the user named in the path, answering 404 if there's none
+// and 403 if the request's token belongs to someone else.
+func (s *Server) pathUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	user, err := s.db.GetUser(r.Context(), r.PathValue("user"))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "user not found: %s", r.PathValue("user"))
+		return user, false
+	}
+	if err != nil {
+		internalError(w, r, err)
+		return user, false
+	}
+	if user.ID != requestUser(r).ID {
+		writeError(w, http.StatusForbidden, "your token only gives access to %s's data", requestUser(r).Name)
+		return user, false
+	}
+	return user, true
+}
+
+func (s *Server) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	users, err := s.db.GetUsers(r.Context())
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	users, more := paginate(users, p)
+	items := make([]apiUser, 0, len(users))
+	for _, user := range users {
+		items = append(items, userJSON(user))
+	}
+	writeJSON(w, http.StatusOK, newList(r, p, items, more))
+}
+
+func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Name) == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	passwordHash, err := auth.HashPassword(body.Password)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	user, err := s.db.CreateUser(r.Context(), database.CreateUserParams{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Name:         body.Name,
+		PasswordHash: sql.NullString{String: passwordHash, Valid: true},
+	})
+	if isUniqueViolation(err) {
+		writeError(w, http.StatusConflict, "user already exists: %s", body.Name)
+		return
+	}
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", APIPrefix+"/users/"+url.PathEscape(user.Name))
+	writeJSON(w, http.StatusCreated, userJSON(user))
+}
+
+func (s *Server) handleGetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathUser(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, userJSON(user))
+}
+
+func (s *Server) handleListFeeds(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	feeds, err := s.db.GetFeeds(r.Context())
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	feeds, more := paginate(feeds, p)
+	items := make([]apiFeed, 0, len(feeds))
+	for _, feed := range feeds {
+		items = append(items, feedJSON(feed))
+	}
+	writeJSON(w, http.StatusOK, newList(r, p, items, more))
+}
+
+func (s *Server) handleGetFeed(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "feed")
+	if !ok {
+		return
+	}
+	feed, err := s.db.GetFeed(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "feed not found: %s", id)
+		return
+	}
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, feedJSON(feed))
+}
+
+// handleCreateFeed adds a feed for the user and follows it, like addfeed.
+func (s *Server) handleCreateFeed(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathUser(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Name) == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if feedURL, err := url.Parse(body.URL); err != nil || (feedURL.Scheme != "http" && feedURL.Scheme != "https") || feedURL.Host == "" {
+		writeError(w, http.StatusBadRequest, "url must be an absolute http or https URL")
+		return
+	}
+
+	feed, err := s.db.CreateFeed(r.Context(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      body.Name,
+		Url:       body.URL,
+		UserID:    uuid.NullUUID{UUID: user.ID, Valid: true},
+	})
+	if isUniqueViolation(err) {
+		writeError(w, http.StatusConflict, "feed already exists: %s", body.URL)
+		return
+	}
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	if _, err := s.follow(r, user, feed); err != nil {
+		internalError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", APIPrefix+"/feeds/"+feed.ID.String())
+	writeJSON(w, http.StatusCreated, feedJSON(feed))
+}
+
+func (s *Server) follow(r *http.Request, user database.User, feed database.Feed) (database.CreateFeedFollowRow, error) {
+	return s.db.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    uuid.NullUUID{UUID: user.ID, Valid: true},
+		FeedID:    uuid.NullUUID{UUID: feed.ID, Valid: true},
+	})
+}
+
+func (s *Server) handleListFollows(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathUser(w, r)
+	if !ok {
+		return
+	}
+	p, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	follows, err := s.db.GetFeedFollowsForUser(r.Context(), uuid.NullUUID{UUID: user.ID, Valid: true})
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	follows, more := paginate(follows, p)
+	items := make([]apiFollow, 0, len(follows))
+	for _, follow := range follows {
+		items = append(items, apiFollow{
+			FeedID:     follow.FeedID.UUID,
+			FeedName:   follow.FeedName,
+			FeedURL:    follow.Url,
+			FollowedAt: follow.CreatedAt,
+			Gone:       follow.DeactivatedAt.Valid,
+		})
+	}
+	writeJSON(w, http.StatusOK, newList(r, p, items, more))
+}
+
+// handleCreateFollow follows a feed given by id or by url.
+func (s *Server) handleCreateFollow(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathUser(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		FeedID uuid.UUID `json:"feed_id"`
+		URL    string    `json:"url"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	var feed database.Feed
+	var err error
+	switch {
+	case body.FeedID != uuid.Nil:
+		feed, err = s.db.GetFeed(r.Context(), body.FeedID)
+	case body.URL != "":
+		feed, err = s.db.GetFeedFromURL(r.Context(), body.URL)
+	default:
+		writeError(w, http.StatusBadRequest, "feed_id or url is required")
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "feed not found")
+		return
+	}
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	follow, err := s.follow(r, user, feed)
+	if isUniqueViolation(err) {
+		writeError(w, http.StatusConflict, "already following %s", feed.Url)
+		return
+	}
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", APIPrefix+"/users/"+url.PathEscape(user.Name)+"/follows/"+feed.ID.String())
+	writeJSON(w, http.StatusCreated, apiFollow{
+		FeedID:     feed.ID,
+		FeedName:   feed.Name,
+		FeedURL:    feed.Url,
+		FollowedAt: follow.CreatedAt,
+		Gone:       feed.DeactivatedAt.Valid,
+	})
+}
+
+func (s *Server) handleDeleteFollow(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathUser(w, r)
+	if !ok {
+		return
+	}
+	feedID, ok := pathID(w, r, "feed")
+	if !ok {
+		return
+	}
+	deleted, err := s.db.DeleteFeedFollow(r.Context(), database.DeleteFeedFollowParams{
+		UserID: uuid.NullUUID{UUID: user.ID, Valid: true},
+		FeedID: uuid.NullUUID{UUID: feedID, Valid: true},
+	})
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	if deleted == 0 {
+		writeError(w, http.StatusNotFound, "not following feed %s", feedID)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListPosts is the user's timeline, filtered like browse.
+func (s *Server) handleListPosts(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathUser(w, r)
+	if !ok {
+		return
+	}
+	p, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	params, err := postFilters(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	params.UserID = uuid.NullUUID{UUID: user.ID, Valid: true}
+	// one more than asked tells whether there's a next page
+	params.RowLimit = int32(p.limit + 1)
+	params.RowOffset = int32(p.offset)
+
+	posts, err := s.db.GetPostsForUser(r.Context(), params)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	more := len(posts) > p.limit
+	if more {
+		posts = posts[:p.limit]
+	}
+	items := make([]apiPost, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, postJSON(post))
+	}
+	writeJSON(w, http.StatusOK, newList(r, p, items, more))
+}
+
+// postFilters reads the timeline filters shared by every endpoint listing
+// posts: feed, since, until, search and sort.
+func postFilters(r *http.Request) (database.GetPostsForUserParams, error) {
+	query := r.URL.Query()
+	params := database.GetPostsForUserParams{SortBy: "published"}
+	if sort := query.Get("sort"); sort != "" {
+		params.SortBy = sort
+	}
+	validSort := false
+	for _, order := range postSortOrders {
+		validSort = validSort || order == params.SortBy
+	}
+	if !validSort {
+		return params, errors.New("sort must be published, oldest, fetched or feed")
+	}
+	if feed := query.Get("feed"); feed != "" {
+		params.Feed = sql.NullString{String: feed, Valid: true}
+	}
+	if search := query.Get("search"); search != "" {
+		params.Search = sql.NullString{String: search, Valid: true}
+	}
+	for _, bound := range []struct {
+		name string
+		dest *sql.NullTime
+	}{{"since", &params.Since}, {"until", &params.Until}} {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t, err = time.Parse(time.DateOnly, value)
+		}
+		if err != nil {
+			return params, errors.New(bound.name + " must be an RFC 3339 time or a date")
+		}
+		*bound.dest = sql.NullTime{Time: t, Valid: true}
+	}
+	return params, nil
+}
+
+// pathPost loads the post in the path as the user sees it, answering 404
+// if there's none.
+func (s *Server) pathPost(w http.ResponseWriter, r *http.Request, user database.User) (database.GetPostForUserRow, bool) {
+	id, ok := pathID(w, r, "post")
+	if !ok {
+		return database.GetPostForUserRow{}, false
+	}
+	post, err := s.db.GetPostForUser(r.Context(), database.GetPostForUserParams{
+		UserID: user.ID,
+		ID:     id,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "post not found: %s", id)
+		return post, false
+	}
+	if err != nil {
+		internalError(w, r, err)
+		return post, false
+	}
+	return post, true
+}
+
+func (s *Server) handleGetPost(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathUser(w, r)
+	if !ok {
+		return
+	}
+	post, ok := s.pathPost(w, r, user)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, postJSON(database.GetPostsForUserRow(post)))
+}
+
+func (s *Server) handleSetRead(read bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := s.pathUser(w, r)
+		if !ok {
+			return
+		}
+		post, ok := s.pathPost(w, r, user)
+		if !ok {
+			return
+		}
+		var err error
+		if read {
+			err = s.db.MarkPostRead(r.Context(), database.MarkPostReadParams{
+				UserID: user.ID,
+				PostID: post.ID,
+				ReadAt: time.Now(),
+			})
+		} else {
+			err = s.db.MarkPostUnread(r.Context(), database.MarkPostUnreadParams{
+				UserID: user.ID,
+				PostID: post.ID,
+			})
+		}
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handleSetStarred(starred bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := s.pathUser(w, r)
+		if !ok {
+			return
+		}
+		post, ok := s.pathPost(w, r, user)
+		if !ok {
+			return
+		}
+		var err error
+		if starred {
+			err = s.db.StarPost(r.Context(), database.StarPostParams{
+				UserID:    user.ID,
+				PostID:    post.ID,
+				StarredAt: time.Now(),
+			})
+		} else {
+			err = s.db.UnstarPost(r.Context(), database.UnstarPostParams{
+				UserID: user.ID,
+				PostID: post.ID,
+			})
+		}
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRequireToken(t *testing.T) {
+	s, alice, _ := newTestServer(t)
+	target := APIPrefix + "/users/alice/posts/" + alice.post.String()
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"no token", nil, http.StatusUnauthorized},
+		{"empty bearer", bearer(""), http.StatusUnauthorized},
+		{"not a bearer token", http.Header{"Authorization": {"Basic " + alice.token}}, http.StatusUnauthorized},
+		{"unknown token", bearer("gator_nobody_token"), http.StatusUnauthorized},
+		{"valid token", bearer(alice.token), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := serve(s, http.MethodGet, target, nil, tt.header)
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.want, readBody(t, resp))
+			}
+			if tt.want == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestOpenAPIWithoutToken(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	resp := serve(s, http.MethodGet, APIPrefix+"/openapi.yaml", nil, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestPostScoping(t *testing.T) {
+	s, alice, bob := newTestServer(t)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{"own post", http.MethodGet, "/users/alice/posts/" + alice.post.String(), http.StatusOK},
+		{"another user's post", http.MethodGet, "/users/alice/posts/" + bob.post.String(), http.StatusNotFound},
+		{"mark another user's post read", http.MethodPut, "/users/alice/posts/" + bob.post.String() + "/read", http.StatusNotFound},
+		{"star another user's post", http.MethodPut, "/users/alice/posts/" + bob.post.String() + "/star", http.StatusNotFound},
+		{"missing post", http.MethodGet, "/users/alice/posts/" + uuid.NewString(), http.StatusNotFound},
+		{"invalid post id", http.MethodGet, "/users/alice/posts/42", http.StatusBadRequest},
+		{"another user's path", http.MethodGet, "/users/bob/posts/" + bob.post.String(), http.StatusForbidden},
+		{"unknown user", http.MethodGet, "/users/carol/posts/" + alice.post.String(), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := serve(s, tt.method, APIPrefix+tt.path, nil, bearer(alice.token))
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.StatusCode, tt.want, readBody(t, resp))
+			}
+		})
+	}
+}
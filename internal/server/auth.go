@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jamistoso/gator/internal/auth"
+	"github.com/jamistoso/gator/internal/database"
+)
+
+type contextKey int
+
+const userKey contextKey = 0
+
+// requireToken only lets requests through that carry a valid API token as a
+// bearer token, and remembers whose token it is.
+func (s *Server) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			unauthorized(w, "an API token is required, create one with 'gator token create'")
+			return
+		}
+		user, err := s.tokenUser(r.Context(), token)
+		if errors.Is(err, sql.ErrNoRows) {
+			unauthorized(w, "invalid or revoked API token")
+			return
+		}
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+	}
+}
+
+// tokenUser looks up whose API token it is, and notes that it was used.
+func (s *Server) tokenUser(ctx context.Context, token string) (database.User, error) {
+	row, err := s.db.GetUserByAPIToken(ctx, auth.HashToken(token))
+	if err != nil {
+		return database.User{}, err
+	}
+	if err := s.db.TouchAPIToken(ctx, row.TokenID); err != nil {
+		// not worth failing the request over
+		log.Printf("recording use of token %s: %v", row.TokenID, err)
+	}
+	return database.User{
+		ID:           row.ID,
+		CreatedAt:    row.CreatedAt,
+		UpdatedAt:    row.UpdatedAt,
+		Name:         row.Name,
+		PasswordHash: row.PasswordHash,
+		FeedSecret:   row.FeedSecret,
+		FeverKeyHash: row.FeverKeyHash,
+	}, nil
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="gator"`)
+	writeError(w, http.StatusUnauthorized, "%s", msg)
+}
+
+// requestUser is the owner of the request's token.
+func requestUser(r *http.Request) database.User {
+	user, _ := r.Context().Value(userKey).(database.User)
+	return user
+}
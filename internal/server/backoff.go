@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// freeLoginFailures is how many wrong passwords in a row go unpunished.
+	freeLoginFailures = 3
+	maxLoginDelay     = 15 * time.Minute
+)
+
+// loginBackoff slows down password guessing. After a few failed logins for
+// a name from the same address, further attempts are turned away for a
+// delay that doubles with each failure, up to maxLoginDelay.
+type loginBackoff struct {
+	mu       sync.Mutex
+	failures map[string]loginFailures
+}
+
+type loginFailures struct {
+	count int
+	last  time.Time
+	until time.Time
+}
+
+// loginKey identifies attempts to log in as name from the request's address.
+func loginKey(r *http.Request, name string) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return strings.ToLower(name) + "\x00" + host
+}
+
+// wait returns how long attempts for key are still refused.
+func (b *loginBackoff) wait(key string, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return max(b.failures[key].until.Sub(now), 0)
+}
+
+func (b *loginBackoff) fail(key string, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures == nil {
+		b.failures = map[string]loginFailures{}
+	}
+	f := b.failures[key]
+	// failures are forgotten once the longest delay has passed without one
+	if now.Sub(f.last) > maxLoginDelay {
+		f = loginFailures{}
+	}
+	f.count++
+	f.last = now
+	if f.count > freeLoginFailures {
+		delay := maxLoginDelay
+		if shift := f.count - freeLoginFailures - 1; shift < 20 {
+			delay = min(time.Second<<shift, maxLoginDelay)
+		}
+		f.until = now.Add(delay)
+	}
+	b.failures[key] = f
+
+	if len(b.failures) > 1000 {
+		for k, f := range b.failures {
+			if now.Sub(f.last) > maxLoginDelay {
+				delete(b.failures, k)
+			}
+		}
+	}
+}
+
+func (b *loginBackoff) succeed(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, key)
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	var b loginBackoff
+	now := time.Date(2024, time.March, 6, 10, 30, 0, 0, time.UTC)
+	for i := 0; i < freeLoginFailures; i++ {
+		b.fail("alice", now)
+		if wait := b.wait("alice", now); wait != 0 {
+			t.Fatalf("wait after %d failures = %s, want 0", i+1, wait)
+		}
+	}
+
+	tests := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+	for _, want := range tests {
+		b.fail("alice", now)
+		if got := b.wait("alice", now); got != want {
+			t.Errorf("wait = %s, want %s", got, want)
+		}
+		now = now.Add(want)
+	}
+	if wait := b.wait("bob", now); wait != 0 {
+		t.Errorf("another name waits %s", wait)
+	}
+
+	for i := 0; i < 30; i++ {
+		b.fail("alice", now)
+	}
+	if got := b.wait("alice", now); got != maxLoginDelay {
+		t.Errorf("wait after many failures = %s, want %s", got, maxLoginDelay)
+	}
+
+	b.succeed("alice")
+	b.fail("alice", now)
+	if wait := b.wait("alice", now); wait != 0 {
+		t.Errorf("wait after a success and one failure = %s, want 0", wait)
+	}
+}
+
+func TestLoginBackoffForgets(t *testing.T) {
+	var b loginBackoff
+	now := time.Date(2024, time.March, 6, 10, 30, 0, 0, time.UTC)
+	for i := 0; i < freeLoginFailures; i++ {
+		b.fail("alice", now)
+	}
+	now = now.Add(maxLoginDelay + time.Second)
+	b.fail("alice", now)
+	if wait := b.wait("alice", now); wait != 0 {
+		t.Errorf("old failures still counted, wait = %s", wait)
+	}
+}
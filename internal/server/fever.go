@@ -0,0 +1,328 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/auth"
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/jamistoso/gator/internal/htmltext"
+)
+
+// FeverPrefix is where the Fever API lives; clients are given its address
+// with the trailing slash.
+const FeverPrefix = "/fever"
+
+const (
+	feverAPIVersion = 3
+	// feverGroupID is the one group every feed is in, gator having no
+	// folders. Group 0 is Fever's name for all feeds.
+	feverGroupID = 1
+	// feverIconID is the only favicon. Gator doesn't fetch site icons, so
+	// every feed gets the same blank one.
+	feverIconID   = 1
+	feverIconData = "image/gif;base64,R0lGODlhAQABAIAAAAAAAP///yH5BAEAAAAALAAAAAABAAEAAAIBRAA7"
+	// feverMaxItems is how many items Fever hands out per request.
+	feverMaxItems = 50
+)
+
+func (s *Server) feverRoutes() {
+	s.mux.HandleFunc(FeverPrefix+"/{$}", s.handleFever)
+}
+
+type feverGroup struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type feverFeedsGroup struct {
+	GroupID int64  `json:"group_id"`
+	FeedIDs string `json:"feed_ids"`
+}
+
+type feverFeed struct {
+	ID                int64  `json:"id"`
+	FaviconID         int64  `json:"favicon_id"`
+	Title             string `json:"title"`
+	URL               string `json:"url"`
+	SiteURL           string `json:"site_url"`
+	IsSpark           int    `json:"is_spark"`
+	LastUpdatedOnTime int64  `json:"last_updated_on_time"`
+}
+
+type feverFavicon struct {
+	ID   int64  `json:"id"`
+	Data string `json:"data"`
+}
+
+type feverItem struct {
+	ID            int64  `json:"id"`
+	FeedID        int64  `json:"feed_id"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	HTML          string `json:"html"`
+	URL           string `json:"url"`
+	IsSaved       int    `json:"is_saved"`
+	IsRead        int    `json:"is_read"`
+	CreatedOnTime int64  `json:"created_on_time"`
+}
+
+func feverBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func joinNumbers(numbers []int64) string {
+	parts := make([]string, 0, len(numbers))
+	for _, number := range numbers {
+		parts = append(parts, strconv.FormatInt(number, 10))
+	}
+	return strings.Join(parts, ",")
+}
+
+// handleFever answers every Fever request. The query says what to return
+// (groups, feeds, favicons, items, links, unread_item_ids, saved_item_ids)
+// and mark, as and id what to change first. Failed logins still answer
+// 200, with auth set to 0.
+func (s *Server) handleFever(w http.ResponseWriter, r *http.Request) {
+	response := map[string]any{"api_version": feverAPIVersion, "auth": 0}
+	key := strings.ToLower(strings.TrimSpace(r.FormValue("api_key")))
+	if key == "" {
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+	user, err := s.db.GetUserByFeverKey(r.Context(), sql.NullString{String: auth.HashToken(key), Valid: true})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	response["auth"] = 1
+	userID := uuid.NullUUID{UUID: user.ID, Valid: true}
+	query := r.URL.Query()
+
+	follows, err := s.db.GetFeedFollowsForUser(r.Context(), userID)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	var lastRefreshed time.Time
+	feedNumbers := make(map[uuid.UUID]int64, len(follows))
+	for _, follow := range follows {
+		feedNumbers[follow.FeedID.UUID] = follow.Number
+		if follow.LastFetchedAt.Time.After(lastRefreshed) {
+			lastRefreshed = follow.LastFetchedAt.Time
+		}
+	}
+	response["last_refreshed_on_time"] = int64(0)
+	if !lastRefreshed.IsZero() {
+		response["last_refreshed_on_time"] = lastRefreshed.Unix()
+	}
+
+	if r.FormValue("mark") != "" {
+		if err := s.feverMark(r, user); err != nil {
+			internalError(w, r, err)
+			return
+		}
+		switch r.FormValue("as") {
+		case "read", "unread":
+			query.Set("unread_item_ids", "")
+		case "saved", "unsaved":
+			query.Set("saved_item_ids", "")
+		}
+	}
+
+	if query.Has("groups") || query.Has("feeds") {
+		feedIDs := make([]int64, 0, len(follows))
+		for _, follow := range follows {
+			feedIDs = append(feedIDs, follow.Number)
+		}
+		response["feeds_groups"] = []feverFeedsGroup{{GroupID: feverGroupID, FeedIDs: joinNumbers(feedIDs)}}
+	}
+	if query.Has("groups") {
+		response["groups"] = []feverGroup{{ID: feverGroupID, Title: "All"}}
+	}
+	if query.Has("feeds") {
+		feeds := make([]feverFeed, 0, len(follows))
+		for _, follow := range follows {
+			feed := feverFeed{
+				ID:        follow.Number,
+				FaviconID: feverIconID,
+				Title:     follow.FeedName,
+				URL:       follow.Url,
+				SiteURL:   follow.Url,
+			}
+			if follow.LastFetchedAt.Valid {
+				feed.LastUpdatedOnTime = follow.LastFetchedAt.Time.Unix()
+			}
+			feeds = append(feeds, feed)
+		}
+		response["feeds"] = feeds
+	}
+	if query.Has("favicons") {
+		response["favicons"] = []feverFavicon{{ID: feverIconID, Data: feverIconData}}
+	}
+	if query.Has("items") {
+		items, total, err := s.feverItems(r, userID, feedNumbers)
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+		response["items"] = items
+		response["total_items"] = total
+	}
+	if query.Has("links") {
+		response["links"] = []any{}
+	}
+	for _, ids := range []struct {
+		name   string
+		params database.GetPostNumbersForUserParams
+	}{
+		{"unread_item_ids", database.GetPostNumbersForUserParams{UserID: userID, Read: sql.NullBool{Bool: false, Valid: true}}},
+		{"saved_item_ids", database.GetPostNumbersForUserParams{UserID: userID, Starred: sql.NullBool{Bool: true, Valid: true}}},
+	} {
+		if !query.Has(ids.name) {
+			continue
+		}
+		numbers, err := s.db.GetPostNumbersForUser(r.Context(), ids.params)
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+		response[ids.name] = joinNumbers(numbers)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// feverItems returns up to feverMaxItems items: those listed by with_ids,
+// those before max_id newest first, or else those after since_id.
+func (s *Server) feverItems(r *http.Request, userID uuid.NullUUID, feedNumbers map[uuid.UUID]int64) ([]feverItem, int64, error) {
+	var posts []database.GetPostsForUserRow
+	if withIDs := r.FormValue("with_ids"); withIDs != "" {
+		var numbers []int64
+		for _, id := range strings.Split(withIDs, ",") {
+			number, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64)
+			if err == nil && len(numbers) < feverMaxItems {
+				numbers = append(numbers, number)
+			}
+		}
+		rows, err := s.db.GetPostsForUserByNumbers(r.Context(), database.GetPostsForUserByNumbersParams{
+			UserID:  userID,
+			Numbers: numbers,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, row := range rows {
+			posts = append(posts, database.GetPostsForUserRow(row))
+		}
+	} else {
+		params := database.GetPostsForUserByNumberRangeParams{
+			UserID:   userID,
+			RowLimit: feverMaxItems,
+		}
+		if maxID, err := strconv.ParseInt(r.FormValue("max_id"), 10, 64); err == nil {
+			params.BeforeNumber = sql.NullInt64{Int64: maxID, Valid: true}
+		} else if sinceID, err := strconv.ParseInt(r.FormValue("since_id"), 10, 64); err == nil {
+			params.AfterNumber = sql.NullInt64{Int64: sinceID, Valid: true}
+		}
+		rows, err := s.db.GetPostsForUserByNumberRange(r.Context(), params)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, row := range rows {
+			posts = append(posts, database.GetPostsForUserRow(row))
+		}
+	}
+
+	items := make([]feverItem, 0, len(posts))
+	for _, post := range posts {
+		contentHTML, _ := postContent(post.Description, post.ContentHtml, post.ContentText, post.Url)
+		created := post.CreatedAt
+		if post.PublishedAt.Valid {
+			created = post.PublishedAt.Time
+		}
+		items = append(items, feverItem{
+			ID:            post.Number,
+			FeedID:        feedNumbers[post.FeedID.UUID],
+			Title:         htmltext.Line(post.Title.String),
+			HTML:          contentHTML,
+			URL:           post.Url,
+			IsSaved:       feverBool(post.IsStarred),
+			IsRead:        feverBool(post.IsRead),
+			CreatedOnTime: created.Unix(),
+		})
+	}
+	total, err := s.db.CountPostsForUser(r.Context(), userID)
+	return items, total, err
+}
+
+// feverMark applies mark=item|feed|group, as=read|unread|saved|unsaved and
+// id. Feeds and groups are only ever marked read, up to the before time in
+// seconds. Unknown ids are ignored, as Fever does.
+func (s *Server) feverMark(r *http.Request, user database.User) error {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return nil
+	}
+	userID := uuid.NullUUID{UUID: user.ID, Valid: true}
+
+	if r.FormValue("mark") == "item" {
+		posts, err := s.db.GetPostsForUserByNumbers(r.Context(), database.GetPostsForUserByNumbersParams{
+			UserID:  userID,
+			Numbers: []int64{id},
+		})
+		if err != nil || len(posts) == 0 {
+			return err
+		}
+		post := posts[0]
+		switch r.FormValue("as") {
+		case "read":
+			return s.db.MarkPostRead(r.Context(), database.MarkPostReadParams{UserID: user.ID, PostID: post.ID, ReadAt: time.Now()})
+		case "unread":
+			return s.db.MarkPostUnread(r.Context(), database.MarkPostUnreadParams{UserID: user.ID, PostID: post.ID})
+		case "saved":
+			return s.db.StarPost(r.Context(), database.StarPostParams{UserID: user.ID, PostID: post.ID, StarredAt: time.Now()})
+		case "unsaved":
+			return s.db.UnstarPost(r.Context(), database.UnstarPostParams{UserID: user.ID, PostID: post.ID})
+		}
+		return nil
+	}
+
+	if r.FormValue("as") != "read" {
+		return nil
+	}
+	params := database.MarkPostsReadParams{ReadAt: time.Now(), UserID: userID}
+	if before, err := strconv.ParseInt(r.FormValue("before"), 10, 64); err == nil && before > 0 {
+		params.FetchedBefore = sql.NullTime{Time: time.Unix(before, 0), Valid: true}
+	}
+	switch r.FormValue("mark") {
+	case "feed":
+		feed, err := s.db.GetFeedByNumber(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		params.FeedUrl = sql.NullString{String: feed.Url, Valid: true}
+	case "group":
+		if id != 0 && id != feverGroupID {
+			return nil
+		}
+	default:
+		return nil
+	}
+	_, err = s.db.MarkPostsRead(r.Context(), params)
+	return err
+}
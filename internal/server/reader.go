@@ -0,0 +1,624 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/auth"
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/jamistoso/gator/internal/htmltext"
+)
+
+// ReaderPrefix is where the subset of the Google Reader API spoken by
+// FreshRSS and its mobile clients lives. Clients are pointed at it as their
+// server address.
+const ReaderPrefix = "/greader"
+
+const (
+	readerAPI = ReaderPrefix + "/reader/api/0"
+
+	readerItemPrefix = "tag:google.com,2005:reader/item/"
+	readerFeedPrefix = "feed/"
+	readerStatePath  = "state/com.google/"
+
+	readingList = "user/-/" + readerStatePath + "reading-list"
+	readState   = "user/-/" + readerStatePath + "read"
+	starred     = "user/-/" + readerStatePath + "starred"
+
+	defaultReaderItems = 20
+	// clients ask for the ids of every unread item at once
+	maxReaderIDs   = 10000
+	maxReaderItems = 1000
+)
+
+// errReaderLabel means a stream is a label. Gator has no folders, so they
+// are always empty.
+var errReaderLabel = errors.New("labels are always empty")
+
+func (s *Server) readerRoutes() {
+	// POST only: credentials in a query string end up in access and proxy logs
+	s.mux.HandleFunc("POST "+ReaderPrefix+"/accounts/ClientLogin", s.handleReaderLogin)
+
+	s.mux.HandleFunc("GET "+readerAPI+"/token", s.requireReaderAuth(s.handleReaderToken))
+	s.mux.HandleFunc("GET "+readerAPI+"/user-info", s.requireReaderAuth(s.handleReaderUserInfo))
+	s.mux.HandleFunc("GET "+readerAPI+"/subscription/list", s.requireReaderAuth(s.handleReaderSubscriptions))
+	s.mux.HandleFunc("GET "+readerAPI+"/tag/list", s.requireReaderAuth(s.handleReaderTags))
+	s.mux.HandleFunc("GET "+readerAPI+"/unread-count", s.requireReaderAuth(s.handleReaderUnreadCount))
+	s.mux.HandleFunc("GET "+readerAPI+"/stream/items/ids", s.requireReaderAuth(s.handleReaderItemIDs))
+	s.mux.HandleFunc("GET "+readerAPI+"/stream/items/contents", s.requireReaderAuth(s.handleReaderItemContents))
+	s.mux.HandleFunc("POST "+readerAPI+"/stream/items/contents", s.requireReaderAuth(s.handleReaderItemContents))
+	s.mux.HandleFunc("GET "+readerAPI+"/stream/contents/{stream...}", s.requireReaderAuth(s.handleReaderStream))
+	s.mux.HandleFunc("POST "+readerAPI+"/edit-tag", s.requireReaderAuth(s.handleReaderEditTag))
+	s.mux.HandleFunc("POST "+readerAPI+"/mark-all-as-read", s.requireReaderAuth(s.handleReaderMarkAllRead))
+}
+
+// requireReaderAuth is requireToken for Google Reader clients, which send
+// the token ClientLogin gave them as "GoogleLogin auth=TOKEN".
+func (s *Server) requireReaderAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+		if !ok || token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		user, err := s.tokenUser(r.Context(), token)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+	}
+}
+
+// handleReaderLogin checks a user's name and password and hands out an API
+// token. Each client a user logs in with has one token, named after the
+// client, which a new login replaces; a client that logs in on every sync
+// doesn't pile up tokens.
+func (s *Server) handleReaderLogin(w http.ResponseWriter, r *http.Request) {
+	name := r.PostFormValue("Email")
+	key := loginKey(r, name)
+	if wait := s.readerLogins.wait(key, time.Now()); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Round(time.Second)/time.Second)))
+		http.Error(w, "Error=TooManyAttempts", http.StatusTooManyRequests)
+		return
+	}
+	badAuth := func() {
+		s.readerLogins.fail(key, time.Now())
+		http.Error(w, "Error=BadAuthentication", http.StatusForbidden)
+	}
+	user, err := s.db.GetUser(r.Context(), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		badAuth()
+		return
+	}
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	// users without a password can't log in from elsewhere until they
+	// set one with 'gator passwd'
+	if !user.PasswordHash.Valid || auth.CheckPassword(user.PasswordHash.String, r.PostFormValue("Passwd")) != nil {
+		badAuth()
+		return
+	}
+	s.readerLogins.succeed(key)
+
+	token, hash, prefix, err := auth.NewToken()
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	_, err = s.db.ReplaceAPIToken(r.Context(), database.ReplaceAPITokenParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UserID:      user.ID,
+		Name:        "Google Reader login (" + readerClient(r) + ")",
+		TokenHash:   hash,
+		TokenPrefix: prefix,
+	})
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "SID=%s\nLSID=%s\nAuth=%s\n", token, token, token)
+}
+
+// readerClient is the name a client gave itself when logging in, cut down
+// to something fit for 'gator token list'.
+func readerClient(r *http.Request) string {
+	client := strings.Join(strings.FieldsFunc(r.PostFormValue("client"), func(c rune) bool {
+		return unicode.IsSpace(c) || unicode.IsControl(c)
+	}), " ")
+	if client == "" {
+		return "unknown client"
+	}
+	if runes := []rune(client); len(runes) > 40 {
+		client = string(runes[:40])
+	}
+	return client
+}
+
+// handleReaderToken answers with the token clients send back as T with
+// every POST. Those requests are authenticated by their header anyway, so
+// it isn't checked.
+func (s *Server) handleReaderToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, strings.ReplaceAll(requestUser(r).ID.String(), "-", ""))
+}
+
+func (s *Server) handleReaderUserInfo(w http.ResponseWriter, r *http.Request) {
+	user := requestUser(r)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"userId":        user.ID.String(),
+		"userName":      user.Name,
+		"userProfileId": user.ID.String(),
+		"userEmail":     "",
+	})
+}
+
+type readerSubscription struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Categories []string `json:"categories"`
+	URL        string   `json:"url"`
+	HTMLURL    string   `json:"htmlUrl"`
+	IconURL    string   `json:"iconUrl"`
+}
+
+func (s *Server) handleReaderSubscriptions(w http.ResponseWriter, r *http.Request) {
+	follows, err := s.db.GetFeedFollowsForUser(r.Context(), uuid.NullUUID{UUID: requestUser(r).ID, Valid: true})
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	subscriptions := make([]readerSubscription, 0, len(follows))
+	for _, follow := range follows {
+		subscriptions = append(subscriptions, readerSubscription{
+			ID:         readerFeedPrefix + follow.Url,
+			Title:      follow.FeedName,
+			Categories: []string{},
+			URL:        follow.Url,
+			HTMLURL:    follow.Url,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"subscriptions": subscriptions})
+}
+
+func (s *Server) handleReaderTags(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tags": []map[string]string{{"id": starred}},
+	})
+}
+
+type readerUnreadCount struct {
+	ID    string `json:"id"`
+	Count int64  `json:"count"`
+}
+
+func (s *Server) handleReaderUnreadCount(w http.ResponseWriter, r *http.Request) {
+	feeds, err := s.db.GetFollowedFeedsWithUnreadCounts(r.Context(), uuid.NullUUID{UUID: requestUser(r).ID, Valid: true})
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	total := readerUnreadCount{ID: readingList}
+	counts := []readerUnreadCount{}
+	for _, feed := range feeds {
+		counts = append(counts, readerUnreadCount{ID: readerFeedPrefix + feed.Url, Count: feed.UnreadCount})
+		total.Count += feed.UnreadCount
+	}
+	counts = append(counts, total)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"max":          maxReaderIDs,
+		"unreadcounts": counts,
+	})
+}
+
+// readerState returns the name of a user/USER/state/com.google/NAME stream,
+// where USER may be "-" for the requesting user.
+func readerState(stream string) (string, bool) {
+	rest, ok := strings.CutPrefix(stream, "user/")
+	if !ok {
+		return "", false
+	}
+	_, path, _ := strings.Cut(rest, "/")
+	return strings.CutPrefix(path, readerStatePath)
+}
+
+func isReaderLabel(stream string) bool {
+	rest, ok := strings.CutPrefix(stream, "user/")
+	if !ok {
+		return false
+	}
+	_, path, _ := strings.Cut(rest, "/")
+	return strings.HasPrefix(path, "label/")
+}
+
+// readerStreamFilter narrows params down to the posts in a stream, or
+// includes or excludes those in a state stream when exclude is set.
+func readerStreamFilter(params *database.GetPostsForUserParams, stream string, exclude bool) error {
+	if feedURL, ok := strings.CutPrefix(stream, readerFeedPrefix); ok && !exclude {
+		params.Feed = sql.NullString{String: feedURL, Valid: true}
+		return nil
+	}
+	if isReaderLabel(stream) {
+		return errReaderLabel
+	}
+	state, ok := readerState(stream)
+	switch {
+	case !ok:
+		return fmt.Errorf("unknown stream %q", stream)
+	case state == "reading-list":
+	case state == "read":
+		params.Read = sql.NullBool{Bool: !exclude, Valid: true}
+	case state == "starred":
+		params.Starred = sql.NullBool{Bool: !exclude, Valid: true}
+	default:
+		return fmt.Errorf("unknown stream %q", stream)
+	}
+	return nil
+}
+
+// readerQuery turns a stream request's parameters into a query: s (or the
+// path) picks the stream, xt excludes and it includes a state, n caps the
+// number of items, c continues where the last page ended, r=o puts the
+// oldest first and ot and nt bound publication times in seconds.
+func readerQuery(r *http.Request, stream string, maxItems int) (database.GetPostsForUserParams, error) {
+	params := database.GetPostsForUserParams{
+		UserID:   uuid.NullUUID{UUID: requestUser(r).ID, Valid: true},
+		SortBy:   "published",
+		RowLimit: defaultReaderItems,
+	}
+	if stream == "" {
+		stream = readingList
+	}
+	if err := readerStreamFilter(&params, stream, false); err != nil {
+		return params, err
+	}
+	if xt := r.FormValue("xt"); xt != "" {
+		if err := readerStreamFilter(&params, xt, true); err != nil {
+			return params, err
+		}
+	}
+	if it := r.FormValue("it"); it != "" {
+		if err := readerStreamFilter(&params, it, false); err != nil {
+			return params, err
+		}
+	}
+	if n := r.FormValue("n"); n != "" {
+		limit, err := strconv.Atoi(n)
+		if err != nil || limit < 1 {
+			return params, errors.New("n must be a positive number")
+		}
+		params.RowLimit = int32(min(limit, maxItems))
+	}
+	if c := r.FormValue("c"); c != "" {
+		offset, err := strconv.Atoi(c)
+		if err != nil || offset < 0 {
+			return params, errors.New("invalid continuation")
+		}
+		params.RowOffset = int32(offset)
+	}
+	if r.FormValue("r") == "o" {
+		params.SortBy = "oldest"
+	}
+	for _, bound := range []struct {
+		name string
+		dest *sql.NullTime
+	}{{"ot", &params.Since}, {"nt", &params.Until}} {
+		value := r.FormValue(bound.name)
+		if value == "" {
+			continue
+		}
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return params, fmt.Errorf("%s must be a time in seconds", bound.name)
+		}
+		*bound.dest = sql.NullTime{Time: time.Unix(seconds, 0), Valid: true}
+	}
+	return params, nil
+}
+
+// readerPosts runs a stream request, answering for itself if it can't.
+// The continuation is empty on the last page.
+func (s *Server) readerPosts(w http.ResponseWriter, r *http.Request, stream string, maxItems int) ([]database.GetPostsForUserRow, string, bool) {
+	params, err := readerQuery(r, stream, maxItems)
+	if errors.Is(err, errReaderLabel) {
+		return nil, "", true
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, "", false
+	}
+	limit := int(params.RowLimit)
+	// one more than asked tells whether there's a next page
+	params.RowLimit++
+	posts, err := s.db.GetPostsForUser(r.Context(), params)
+	if err != nil {
+		internalError(w, r, err)
+		return nil, "", false
+	}
+	if len(posts) <= limit {
+		return posts, "", true
+	}
+	return posts[:limit], strconv.Itoa(int(params.RowOffset) + limit), true
+}
+
+type readerItemRef struct {
+	ID              string   `json:"id"`
+	DirectStreamIDs []string `json:"directStreamIds"`
+	TimestampUsec   string   `json:"timestampUsec"`
+}
+
+func (s *Server) handleReaderItemIDs(w http.ResponseWriter, r *http.Request) {
+	posts, continuation, ok := s.readerPosts(w, r, r.FormValue("s"), maxReaderIDs)
+	if !ok {
+		return
+	}
+	refs := make([]readerItemRef, 0, len(posts))
+	for _, post := range posts {
+		refs = append(refs, readerItemRef{
+			ID:              strconv.FormatInt(post.Number, 10),
+			DirectStreamIDs: []string{},
+			TimestampUsec:   strconv.FormatInt(post.CreatedAt.UnixMicro(), 10),
+		})
+	}
+	response := map[string]any{"itemRefs": refs}
+	if continuation != "" {
+		response["continuation"] = continuation
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+type readerLink struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type readerContent struct {
+	Direction string `json:"direction"`
+	Content   string `json:"content"`
+}
+
+type readerOrigin struct {
+	StreamID string `json:"streamId"`
+	Title    string `json:"title"`
+	HTMLURL  string `json:"htmlUrl"`
+}
+
+type readerItem struct {
+	ID            string        `json:"id"`
+	CrawlTimeMsec string        `json:"crawlTimeMsec"`
+	TimestampUsec string        `json:"timestampUsec"`
+	Published     int64         `json:"published"`
+	Updated       int64         `json:"updated"`
+	Title         string        `json:"title"`
+	Canonical     []readerLink  `json:"canonical"`
+	Alternate     []readerLink  `json:"alternate"`
+	Summary       readerContent `json:"summary"`
+	Categories    []string      `json:"categories"`
+	Origin        readerOrigin  `json:"origin"`
+}
+
+func readerItemJSON(post database.GetPostsForUserRow) readerItem {
+	contentHTML, _ := postContent(post.Description, post.ContentHtml, post.ContentText, post.Url)
+	published := post.CreatedAt
+	if post.PublishedAt.Valid {
+		published = post.PublishedAt.Time
+	}
+	categories := []string{readingList}
+	if post.IsRead {
+		categories = append(categories, readState)
+	}
+	if post.IsStarred {
+		categories = append(categories, starred)
+	}
+	return readerItem{
+		ID:            fmt.Sprintf("%s%016x", readerItemPrefix, post.Number),
+		CrawlTimeMsec: strconv.FormatInt(post.CreatedAt.UnixMilli(), 10),
+		TimestampUsec: strconv.FormatInt(post.CreatedAt.UnixMicro(), 10),
+		Published:     published.Unix(),
+		Updated:       post.UpdatedAt.Unix(),
+		Title:         htmltext.Line(post.Title.String),
+		Canonical:     []readerLink{{Href: post.Url}},
+		Alternate:     []readerLink{{Href: post.Url, Type: "text/html"}},
+		Summary:       readerContent{Direction: "ltr", Content: contentHTML},
+		Categories:    categories,
+		Origin: readerOrigin{
+			StreamID: readerFeedPrefix + post.FeedUrl,
+			Title:    post.FeedName,
+			HTMLURL:  post.FeedUrl,
+		},
+	}
+}
+
+func writeReaderItems(w http.ResponseWriter, stream string, posts []database.GetPostsForUserRow, continuation string) {
+	items := make([]readerItem, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, readerItemJSON(post))
+	}
+	response := map[string]any{
+		"direction": "ltr",
+		"id":        stream,
+		"updated":   time.Now().Unix(),
+		"items":     items,
+	}
+	if continuation != "" {
+		response["continuation"] = continuation
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleReaderStream(w http.ResponseWriter, r *http.Request) {
+	stream := r.PathValue("stream")
+	if stream == "" {
+		stream = r.FormValue("s")
+	}
+	posts, continuation, ok := s.readerPosts(w, r, stream, maxReaderItems)
+	if !ok {
+		return
+	}
+	writeReaderItems(w, stream, posts, continuation)
+}
+
+// parseReaderItemID accepts both the long hexadecimal and the short
+// decimal form of item ids.
+func parseReaderItemID(id string) (int64, error) {
+	if hex, ok := strings.CutPrefix(id, readerItemPrefix); ok {
+		number, err := strconv.ParseUint(hex, 16, 64)
+		return int64(number), err
+	}
+	return strconv.ParseInt(id, 10, 64)
+}
+
+// readerItemPosts loads the posts named by the request's i parameters,
+// answering for itself if it can't.
+func (s *Server) readerItemPosts(w http.ResponseWriter, r *http.Request) ([]database.GetPostsForUserRow, bool) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	numbers := make([]int64, 0, len(r.Form["i"]))
+	for _, id := range r.Form["i"] {
+		number, err := parseReaderItemID(id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid item id %q", id), http.StatusBadRequest)
+			return nil, false
+		}
+		numbers = append(numbers, number)
+	}
+	rows, err := s.db.GetPostsForUserByNumbers(r.Context(), database.GetPostsForUserByNumbersParams{
+		UserID:  uuid.NullUUID{UUID: requestUser(r).ID, Valid: true},
+		Numbers: numbers,
+	})
+	if err != nil {
+		internalError(w, r, err)
+		return nil, false
+	}
+	posts := make([]database.GetPostsForUserRow, 0, len(rows))
+	for _, row := range rows {
+		posts = append(posts, database.GetPostsForUserRow(row))
+	}
+	return posts, true
+}
+
+func (s *Server) handleReaderItemContents(w http.ResponseWriter, r *http.Request) {
+	posts, ok := s.readerItemPosts(w, r)
+	if !ok {
+		return
+	}
+	writeReaderItems(w, readingList, posts, "")
+}
+
+// handleReaderEditTag adds the a and removes the r states of the items.
+// Only read, kept-unread and starred mean anything to gator; labels are
+// ignored.
+func (s *Server) handleReaderEditTag(w http.ResponseWriter, r *http.Request) {
+	posts, ok := s.readerItemPosts(w, r)
+	if !ok {
+		return
+	}
+	user := requestUser(r)
+	var edits []func(database.GetPostsForUserRow) error
+	for _, change := range []struct {
+		param string
+		add   bool
+	}{{"a", true}, {"r", false}} {
+		for _, tag := range r.Form[change.param] {
+			state, _ := readerState(tag)
+			add := change.add
+			switch state {
+			case "kept-unread":
+				add = !add
+				fallthrough
+			case "read":
+				edits = append(edits, func(post database.GetPostsForUserRow) error {
+					if add {
+						return s.db.MarkPostRead(r.Context(), database.MarkPostReadParams{
+							UserID: user.ID,
+							PostID: post.ID,
+							ReadAt: time.Now(),
+						})
+					}
+					return s.db.MarkPostUnread(r.Context(), database.MarkPostUnreadParams{
+						UserID: user.ID,
+						PostID: post.ID,
+					})
+				})
+			case "starred":
+				edits = append(edits, func(post database.GetPostsForUserRow) error {
+					if add {
+						return s.db.StarPost(r.Context(), database.StarPostParams{
+							UserID:    user.ID,
+							PostID:    post.ID,
+							StarredAt: time.Now(),
+						})
+					}
+					return s.db.UnstarPost(r.Context(), database.UnstarPostParams{
+						UserID: user.ID,
+						PostID: post.ID,
+					})
+				})
+			}
+		}
+	}
+	for _, post := range posts {
+		for _, edit := range edits {
+			if err := edit(post); err != nil {
+				internalError(w, r, err)
+				return
+			}
+		}
+	}
+	writeReaderOK(w)
+}
+
+// handleReaderMarkAllRead marks the posts of the s stream read, only those
+// fetched by the time in microseconds ts when it's given.
+func (s *Server) handleReaderMarkAllRead(w http.ResponseWriter, r *http.Request) {
+	params := database.MarkPostsReadParams{
+		ReadAt: time.Now(),
+		UserID: uuid.NullUUID{UUID: requestUser(r).ID, Valid: true},
+	}
+	stream := r.FormValue("s")
+	if feedURL, ok := strings.CutPrefix(stream, readerFeedPrefix); ok {
+		params.FeedUrl = sql.NullString{String: feedURL, Valid: true}
+	} else if isReaderLabel(stream) {
+		writeReaderOK(w)
+		return
+	} else if state, _ := readerState(stream); state != "reading-list" {
+		http.Error(w, fmt.Sprintf("can't mark stream %q read", stream), http.StatusBadRequest)
+		return
+	}
+	if ts := r.FormValue("ts"); ts != "" {
+		micros, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			http.Error(w, "ts must be a time in microseconds", http.StatusBadRequest)
+			return
+		}
+		params.FetchedBefore = sql.NullTime{Time: time.UnixMicro(micros), Valid: true}
+	}
+	if _, err := s.db.MarkPostsRead(r.Context(), params); err != nil {
+		internalError(w, r, err)
+		return
+	}
+	writeReaderOK(w)
+}
+
+func writeReaderOK(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, "OK")
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// clientLogin posts a ClientLogin form and returns the response and, if it
+// succeeded, the token.
+func clientLogin(t *testing.T, s *Server, name, password, client string) (*http.Response, string) {
+	t.Helper()
+	form := url.Values{"Email": {name}, "Passwd": {password}, "client": {client}}
+	resp := serve(s, http.MethodPost, ReaderPrefix+"/accounts/ClientLogin", strings.NewReader(form.Encode()),
+		http.Header{"Content-Type": {"application/x-www-form-urlencoded"}})
+	body := readBody(t, resp)
+	_, token, _ := strings.Cut(body, "Auth=")
+	return resp, token
+}
+
+func readerUserInfo(s *Server, token string) int {
+	resp := serve(s, http.MethodGet, readerAPI+"/user-info", nil,
+		http.Header{"Authorization": {"GoogleLogin auth=" + token}})
+	return resp.StatusCode
+}
+
+func TestReaderLoginReplacesToken(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	resp, first := clientLogin(t, s, "alice", testPassword, "Reeder")
+	if resp.StatusCode != http.StatusOK || first == "" {
+		t.Fatalf("login status = %d, token %q", resp.StatusCode, first)
+	}
+	if status := readerUserInfo(s, first); status != http.StatusOK {
+		t.Fatalf("user-info with the new token = %d, want %d", status, http.StatusOK)
+	}
+
+	_, other := clientLogin(t, s, "alice", testPassword, "NetNewsWire")
+	_, second := clientLogin(t, s, "alice", testPassword, "Reeder")
+	if status := readerUserInfo(s, first); status != http.StatusUnauthorized {
+		t.Errorf("user-info with the replaced token = %d, want %d", status, http.StatusUnauthorized)
+	}
+	for _, token := range []string{other, second} {
+		if status := readerUserInfo(s, token); status != http.StatusOK {
+			t.Errorf("user-info with a current token = %d, want %d", status, http.StatusOK)
+		}
+	}
+}
+
+func TestReaderLoginBackoff(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	for i := 0; i < freeLoginFailures; i++ {
+		if resp, _ := clientLogin(t, s, "alice", "wrong password", "Reeder"); resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("failure %d: status = %d, want %d", i+1, resp.StatusCode, http.StatusForbidden)
+		}
+	}
+	if resp, _ := clientLogin(t, s, "alice", "wrong password", "Reeder"); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+
+	// now even the right password has to wait
+	resp, _ := clientLogin(t, s, "Alice", testPassword, "Reeder")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+	if resp.Header.Get("Retry-After") != "1" {
+		t.Errorf("Retry-After = %q, want %q", resp.Header.Get("Retry-After"), "1")
+	}
+
+	if resp, _ := clientLogin(t, s, "bob", "wrong password", "Reeder"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("another name: status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestReaderLoginWithoutPassword(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	if resp, _ := clientLogin(t, s, "bob", "", "Reeder"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestReaderClient(t *testing.T) {
+	tests := []struct {
+		client string
+		want   string
+	}{
+		{"Reeder", "Reeder"},
+		{"  Net\tNews\nWire ", "Net News Wire"},
+		{"", "unknown client"},
+		{strings.Repeat("x", 50), strings.Repeat("x", 40)},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{"client": {tt.client}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if got := readerClient(r); got != tt.want {
+			t.Errorf("readerClient(%q) = %q, want %q", tt.client, got, tt.want)
+		}
+	}
+}
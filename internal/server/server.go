@@ -0,0 +1,163 @@
+// Package server serves gator's data over HTTP.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+
+	// maxBodySize bounds the JSON a client may send.
+	maxBodySize = 1 << 20
+)
+
+// Server routes HTTP requests to handlers backed by the gator database.
+type Server struct {
+	db  *database.Queries
+	mux *http.ServeMux
+
+	readerLogins loginBackoff
+}
+
+func New(db *database.Queries) *Server {
+	s := &Server{
+		db:  db,
+		mux: http.NewServeMux(),
+	}
+	s.apiRoutes()
+	s.timelineRoutes()
+	s.readerRoutes()
+	s.feverRoutes()
+	s.webRoutes()
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, errorResponse{Error: fmt.Sprintf(format, args...)})
+}
+
+// internalError logs what went wrong and tells the client no more than that
+// it wasn't their fault.
+func internalError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	writeError(w, http.StatusInternalServerError, "internal server error")
+}
+
+// decodeJSON reads a request body into v, answering 400 if it can't.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return false
+	}
+	return true
+}
+
+// pathID parses a UUID path parameter, answering 400 if it isn't one.
+func pathID(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue(name))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid %s id %q", name, r.PathValue(name))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// page is the slice of a collection a client asked for with ?limit and
+// ?offset.
+type page struct {
+	limit  int
+	offset int
+}
+
+func parsePage(r *http.Request) (page, error) {
+	p := page{limit: defaultPageLimit}
+	query := r.URL.Query()
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return p, fmt.Errorf("limit must be a number from 1 to %d", maxPageLimit)
+		}
+		p.limit = limit
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return p, errors.New("offset must be a number from 0")
+		}
+		p.offset = offset
+	}
+	return p, nil
+}
+
+// list is the envelope of every paginated response. Next links to the
+// following page when there is one.
+type list[T any] struct {
+	Items  []T    `json:"items"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Next   string `json:"next,omitempty"`
+}
+
+func newList[T any](r *http.Request, p page, items []T, more bool) list[T] {
+	if items == nil {
+		items = []T{}
+	}
+	l := list[T]{
+		Items:  items,
+		Limit:  p.limit,
+		Offset: p.offset,
+	}
+	if more {
+		query := r.URL.Query()
+		query.Set("limit", strconv.Itoa(p.limit))
+		query.Set("offset", strconv.Itoa(p.offset+p.limit))
+		next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		l.Next = next.String()
+	}
+	return l
+}
+
+// paginate cuts a page out of a collection loaded whole.
+func paginate[T any](items []T, p page) ([]T, bool) {
+	if p.offset >= len(items) {
+		return nil, false
+	}
+	items = items[p.offset:]
+	if len(items) > p.limit {
+		return items[:p.limit], true
+	}
+	return items, false
+}
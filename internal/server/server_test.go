@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/auth"
+	"github.com/jamistoso/gator/internal/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeDB stands in for Postgres by answering the few queries the tests
+// reach, picked by their sqlc name. Posts are only found for users following
+// their feed, as GetPostForUser does.
+type fakeDB struct {
+	users   []database.User
+	tokens  map[string]uuid.UUID // token hash to user id
+	follows map[uuid.UUID][]uuid.UUID
+	posts   map[uuid.UUID]uuid.UUID // post id to feed id
+	// named holds the hash of each named token, keyed by user id and name
+	named map[string]string
+}
+
+type testUser struct {
+	user  database.User
+	token string
+	post  uuid.UUID
+}
+
+// testPassword is alice's; bob has none.
+const testPassword = "correct horse battery"
+
+// newTestServer serves users alice and bob, each with a token and a post in
+// a feed only they follow.
+func newTestServer(t *testing.T) (*Server, *testUser, *testUser) {
+	t.Helper()
+	db := &fakeDB{
+		tokens:  map[string]uuid.UUID{},
+		follows: map[uuid.UUID][]uuid.UUID{},
+		posts:   map[uuid.UUID]uuid.UUID{},
+		named:   map[string]string{},
+	}
+	newUser := func(name string) *testUser {
+		u := &testUser{
+			user: database.User{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Name:      name,
+			},
+			token: "gator_" + name + "_token",
+			post:  uuid.New(),
+		}
+		feedID := uuid.New()
+		db.users = append(db.users, u.user)
+		db.tokens[auth.HashToken(u.token)] = u.user.ID
+		db.follows[u.user.ID] = []uuid.UUID{feedID}
+		db.posts[u.post] = feedID
+		return u
+	}
+	alice, bob := newUser("alice"), newUser("bob")
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.users[0].PasswordHash = sql.NullString{String: string(hash), Valid: true}
+	alice.user = db.users[0]
+
+	conn := sql.OpenDB(db)
+	t.Cleanup(func() { conn.Close() })
+	return New(database.New(conn)), alice, bob
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+var queryName = regexp.MustCompile(`^-- name: (\w+)`)
+
+func (c fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	switch name := queryName.FindStringSubmatch(query)[1]; name {
+	case "TouchAPIToken", "MarkPostRead", "MarkPostUnread":
+		return driver.RowsAffected(1), nil
+	default:
+		return nil, fmt.Errorf("unexpected exec %s", name)
+	}
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	arg := func(i int) string { return fmt.Sprint(args[i].Value) }
+	switch name := queryName.FindStringSubmatch(query)[1]; name {
+	case "GetUserByAPIToken":
+		id, ok := c.db.tokens[arg(0)]
+		if !ok {
+			return &fakeRows{}, nil
+		}
+		user := c.db.user(func(u database.User) bool { return u.ID == id })
+		return &fakeRows{rows: [][]driver.Value{append(userValues(user), uuid.NewString())}}, nil
+	case "GetUser":
+		user := c.db.user(func(u database.User) bool { return u.Name == arg(0) })
+		if user == nil {
+			return &fakeRows{}, nil
+		}
+		return &fakeRows{rows: [][]driver.Value{userValues(user)}}, nil
+	case "ReplaceAPIToken":
+		userID, name, hash := uuid.MustParse(arg(2)), arg(3), arg(4)
+		delete(c.db.tokens, c.db.named[userID.String()+name])
+		c.db.named[userID.String()+name] = hash
+		c.db.tokens[hash] = userID
+		return &fakeRows{rows: [][]driver.Value{{
+			arg(0), time.Now(), userID.String(), name, hash, arg(5), nil,
+		}}}, nil
+	case "GetPostForUser":
+		userID, postID := uuid.MustParse(arg(0)), uuid.MustParse(arg(1))
+		feedID, ok := c.db.posts[postID]
+		if !ok || !containsID(c.db.follows[userID], feedID) {
+			return &fakeRows{}, nil
+		}
+		now := time.Now()
+		return &fakeRows{rows: [][]driver.Value{{
+			postID.String(), now, now, "A post", "https://example.com/post", nil, nil,
+			feedID.String(), nil, nil, int64(1), nil, []byte("{}"),
+			"A feed", "https://example.com/feed", false, false,
+		}}}, nil
+	default:
+		return nil, fmt.Errorf("unexpected query %s", name)
+	}
+}
+
+func (db *fakeDB) user(match func(database.User) bool) *database.User {
+	for i := range db.users {
+		if match(db.users[i]) {
+			return &db.users[i]
+		}
+	}
+	return nil
+}
+
+func userValues(u *database.User) []driver.Value {
+	var passwordHash driver.Value
+	if u.PasswordHash.Valid {
+		passwordHash = u.PasswordHash.String
+	}
+	return []driver.Value{u.ID.String(), u.CreatedAt, u.UpdatedAt, u.Name, passwordHash, nil, nil}
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+// Columns only needs the right count; sqlc scans by position.
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// serve sends one request through s and returns the response.
+func serve(s *Server, method, target string, body io.Reader, header http.Header) *http.Response {
+	r := httptest.NewRequest(method, target, body)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w.Result()
+}
+
+func bearer(token string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(b))
+}
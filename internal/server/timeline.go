@@ -0,0 +1,135 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/jamistoso/gator/internal/htmltext"
+	"github.com/jamistoso/gator/internal/syndication"
+)
+
+// TimelinePrefix is where users' timelines are served as feeds. The secret
+// in their URLs stands in for a token, which feed readers can't send.
+const TimelinePrefix = "/timeline"
+
+// timelineMaxAge is how long clients may reuse a timeline before asking
+// again.
+const timelineMaxAge = 5 * time.Minute
+
+func (s *Server) timelineRoutes() {
+	for _, format := range syndication.Formats {
+		s.mux.HandleFunc("GET "+TimelinePrefix+"/{secret}/"+format, s.handleTimeline(format))
+	}
+}
+
+// TimelinePath is the path of the timeline feed with the given secret.
+func TimelinePath(secret, format string) string {
+	return TimelinePrefix + "/" + secret + "/" + format
+}
+
+// WriteTimeline writes the user's posts as an Atom or RSS document. Link is
+// the address the document is published at, if it's known.
+func WriteTimeline(w io.Writer, format string, user database.User, posts []database.GetPostsForUserRow, link string) error {
+	feed := syndication.Feed{
+		ID:       "urn:uuid:" + user.ID.String(),
+		Title:    fmt.Sprintf("%s's timeline", user.Name),
+		Subtitle: fmt.Sprintf("Posts from the feeds %s follows on gator", user.Name),
+		Author:   user.Name,
+		Link:     link,
+		Updated:  timelineUpdated(user, posts),
+	}
+	for _, post := range posts {
+		contentHTML, _ := postContent(post.Description, post.ContentHtml, post.ContentText, post.Url)
+		entry := syndication.Entry{
+			ID:          "urn:uuid:" + post.ID.String(),
+			Title:       htmltext.Line(post.Title.String),
+			Link:        post.Url,
+			Updated:     post.UpdatedAt,
+			ContentHTML: contentHTML,
+			SourceTitle: post.FeedName,
+			SourceURL:   post.FeedUrl,
+		}
+		if post.PublishedAt.Valid {
+			entry.Published = post.PublishedAt.Time
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return syndication.Write(w, format, feed)
+}
+
+// timelineUpdated is when the newest of the posts last changed, or when the
+// user was created if there are none, so it only moves with the document.
+func timelineUpdated(user database.User, posts []database.GetPostsForUserRow) time.Time {
+	updated := user.CreatedAt
+	for _, post := range posts {
+		if post.UpdatedAt.After(updated) {
+			updated = post.UpdatedAt
+		}
+	}
+	return updated
+}
+
+// handleTimeline serves the timeline of the user owning the secret in the
+// path, filtered and paged like the posts endpoint.
+func (s *Server) handleTimeline(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.db.GetUserByFeedSecret(r.Context(), sql.NullString{String: r.PathValue("secret"), Valid: true})
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "timeline not found")
+			return
+		}
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+		p, err := parsePage(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		params, err := postFilters(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		params.UserID = uuid.NullUUID{UUID: user.ID, Valid: true}
+		params.RowLimit = int32(p.limit)
+		params.RowOffset = int32(p.offset)
+		posts, err := s.db.GetPostsForUser(r.Context(), params)
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+
+		var doc bytes.Buffer
+		if err := WriteTimeline(&doc, format, user, posts, requestURL(r)); err != nil {
+			internalError(w, r, err)
+			return
+		}
+		sum := sha256.Sum256(doc.Bytes())
+		w.Header().Set("Content-Type", syndication.ContentType(format)+"; charset=utf-8")
+		// the URL is a secret, so shared caches must not keep it
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(timelineMaxAge.Seconds())))
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+		// ServeContent answers conditional requests with 304
+		http.ServeContent(w, r, "", timelineUpdated(user, posts), bytes.NewReader(doc.Bytes()))
+	}
+}
+
+// requestURL rebuilds the absolute URL a request was made to.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
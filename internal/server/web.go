@@ -0,0 +1,594 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/auth"
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/jamistoso/gator/internal/htmltext"
+)
+
+const (
+	// sessionCookie holds the API token a browser logged in with.
+	sessionCookie = "gator_session"
+	sessionMaxAge = 30 * 24 * time.Hour
+
+	webPageSize = 25
+)
+
+const sessionKey contextKey = 1
+
+//go:embed web
+var webFiles embed.FS
+
+// webTemplates holds each page parsed along with the layout it fills in.
+var webTemplates = parseWebTemplates()
+
+func parseWebTemplates() map[string]*template.Template {
+	funcs := template.FuncMap{
+		"date": func(t time.Time) string { return t.Format("2 Jan 2006 15:04") },
+	}
+	pages := map[string]*template.Template{}
+	for _, page := range []string{"error", "login", "posts", "post", "feeds"} {
+		pages[page] = template.Must(template.New(page).Funcs(funcs).ParseFS(webFiles, "web/layout.html", "web/"+page+".html"))
+	}
+	return pages
+}
+
+func (s *Server) webRoutes() {
+	static, _ := fs.Sub(webFiles, "web/static")
+	s.mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+
+	s.mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/posts", http.StatusSeeOther)
+	})
+	s.mux.HandleFunc("GET /login", s.handleLoginPage)
+	s.mux.HandleFunc("POST /login", s.handleLogin)
+	s.mux.HandleFunc("POST /logout", s.requireSession(s.handleLogout))
+
+	s.mux.HandleFunc("GET /posts", s.requireSession(s.handlePostsPage))
+	s.mux.HandleFunc("GET /posts/{post}", s.requireSession(s.handlePostPage))
+	s.mux.HandleFunc("POST /posts/{post}/read", s.requireSession(s.handleWebSetRead))
+	s.mux.HandleFunc("POST /posts/{post}/star", s.requireSession(s.handleWebSetStarred))
+
+	s.mux.HandleFunc("GET /feeds", s.requireSession(s.handleFeedsPage))
+	s.mux.HandleFunc("POST /feeds", s.requireSession(s.handleWebAddFeed))
+	s.mux.HandleFunc("POST /follows", s.requireSession(s.handleWebFollow))
+	s.mux.HandleFunc("POST /follows/{feed}/delete", s.requireSession(s.handleWebUnfollow))
+}
+
+// webPage is what every page's template gets. Feeds fill the sidebar.
+type webPage struct {
+	Title  string
+	User   *database.User
+	CSRF   string
+	Error  string
+	Feeds  []database.GetFollowedFeedsWithUnreadCountsRow
+	Unread int64
+	// Feed is the url of the feed being shown, if there is one.
+	Feed string
+	Data any
+}
+
+func renderPage(w http.ResponseWriter, r *http.Request, status int, name string, page webPage) {
+	if user, ok := r.Context().Value(userKey).(database.User); ok {
+		page.User = &user
+		page.CSRF = csrfToken(sessionToken(r))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := webTemplates[name].ExecuteTemplate(w, "layout", page); err != nil {
+		log.Printf("%s %s: rendering %s: %v", r.Method, r.URL.Path, name, err)
+	}
+}
+
+// webError shows a page that only says what went wrong.
+func webError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	renderPage(w, r, status, "error", webPage{Title: http.StatusText(status), Error: msg})
+}
+
+func webInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	webError(w, r, http.StatusInternalServerError, "Something went wrong on our side.")
+}
+
+// csrfToken is what forms of a session must send back. Only pages served
+// to the session's browser can know it.
+func csrfToken(session string) string {
+	sum := sha256.Sum256([]byte("csrf\x00" + session))
+	return hex.EncodeToString(sum[:16])
+}
+
+func sessionToken(r *http.Request) string {
+	token, _ := r.Context().Value(sessionKey).(string)
+	return token
+}
+
+// requireSession sends browsers without a valid session to the login page,
+// and turns away form posts without the session's CSRF token.
+func (s *Server) requireSession(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookie)
+		var user database.User
+		if err == nil {
+			user, err = s.tokenUser(r.Context(), cookie.Value)
+		}
+		if err != nil && !errors.Is(err, http.ErrNoCookie) && !errors.Is(err, sql.ErrNoRows) {
+			webInternalError(w, r, err)
+			return
+		}
+		if err != nil {
+			if r.Method != http.MethodGet {
+				webError(w, r, http.StatusUnauthorized, "Your session has ended, please log in again.")
+				return
+			}
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+		if r.Method == http.MethodPost {
+			sent := r.PostFormValue("csrf")
+			if subtle.ConstantTimeCompare([]byte(sent), []byte(csrfToken(cookie.Value))) != 1 {
+				webError(w, r, http.StatusForbidden, "This form has expired, please go back and try again.")
+				return
+			}
+		}
+		ctx := context.WithValue(r.Context(), userKey, user)
+		ctx = context.WithValue(ctx, sessionKey, cookie.Value)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// localPath only lets through paths on this server, so logging in can't
+// send anyone elsewhere. Browsers drop tabs and newlines from URLs, which
+// would turn "/\t/host" into "//host", so paths with control characters are
+// refused.
+func localPath(path, fallback string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return fallback
+	}
+	if strings.ContainsFunc(path, unicode.IsControl) {
+		return fallback
+	}
+	return path
+}
+
+func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	renderPage(w, r, http.StatusOK, "login", webPage{
+		Title: "Log in",
+		Data:  localPath(r.FormValue("next"), "/posts"),
+	})
+}
+
+// handleLogin starts a session backed by a new API token, so 'gator token
+// list' shows it and 'gator token revoke' ends it.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	next := localPath(r.PostFormValue("next"), "/posts")
+	failed := func(msg string) {
+		renderPage(w, r, http.StatusUnauthorized, "login", webPage{Title: "Log in", Error: msg, Data: next})
+	}
+	user, err := s.db.GetUser(r.Context(), r.PostFormValue("name"))
+	if errors.Is(err, sql.ErrNoRows) {
+		failed("Wrong name or password.")
+		return
+	}
+	if err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	if !user.PasswordHash.Valid {
+		failed("This user has no password yet, set one with 'gator passwd'.")
+		return
+	}
+	if auth.CheckPassword(user.PasswordHash.String, r.PostFormValue("password")) != nil {
+		failed("Wrong name or password.")
+		return
+	}
+
+	token, hash, prefix, err := auth.NewToken()
+	if err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	_, err = s.db.CreateAPIToken(r.Context(), database.CreateAPITokenParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UserID:      user.ID,
+		Name:        "Web login " + prefix,
+		TokenHash:   hash,
+		TokenPrefix: prefix,
+	})
+	if err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(sessionMaxAge.Seconds()),
+		Secure:   r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	row, err := s.db.GetUserByAPIToken(r.Context(), auth.HashToken(sessionToken(r)))
+	if err == nil {
+		_, err = s.db.DeleteAPIToken(r.Context(), database.DeleteAPITokenParams{
+			UserID: row.ID,
+			Token:  row.TokenID.String(),
+		})
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		webInternalError(w, r, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1, HttpOnly: true})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// sidebar loads the followed feeds and their unread counts into page.
+func (s *Server) sidebar(r *http.Request, page *webPage) error {
+	feeds, err := s.db.GetFollowedFeedsWithUnreadCounts(r.Context(), uuid.NullUUID{UUID: requestUser(r).ID, Valid: true})
+	if err != nil {
+		return err
+	}
+	page.Feeds = feeds
+	for _, feed := range feeds {
+		page.Unread += feed.UnreadCount
+	}
+	return nil
+}
+
+// webPost is a post as the templates show it.
+type webPost struct {
+	ID        uuid.UUID
+	Title     string
+	URL       string
+	FeedName  string
+	FeedURL   string
+	Published time.Time
+	Read      bool
+	Starred   bool
+	Content   template.HTML
+}
+
+func webPostOf(post database.GetPostsForUserRow) webPost {
+	contentHTML, _ := postContent(post.Description, post.ContentHtml, post.ContentText, post.Url)
+	published := post.CreatedAt
+	if post.PublishedAt.Valid {
+		published = post.PublishedAt.Time
+	}
+	title := htmltext.Line(post.Title.String)
+	if title == "" {
+		title = post.Url
+	}
+	return webPost{
+		ID:        post.ID,
+		Title:     title,
+		URL:       post.Url,
+		FeedName:  post.FeedName,
+		FeedURL:   post.FeedUrl,
+		Published: published,
+		Read:      post.IsRead,
+		Starred:   post.IsStarred,
+		// sanitized when it was stored, or by postContent for older posts
+		Content: template.HTML(contentHTML),
+	}
+}
+
+type webLink struct {
+	Label   string
+	URL     string
+	Current bool
+}
+
+type postsData struct {
+	Posts   []webPost
+	Filters []webLink
+	PrevURL string
+	NextURL string
+}
+
+// handlePostsPage lists the user's posts, webPageSize at a time, from one
+// feed when ?feed is given and only unread or starred ones when ?show says
+// so.
+func (s *Server) handlePostsPage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	pageNumber := 1
+	if value := query.Get("page"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			webError(w, r, http.StatusBadRequest, "The page must be a number from 1.")
+			return
+		}
+		pageNumber = n
+	}
+	params := database.GetPostsForUserParams{
+		UserID:    uuid.NullUUID{UUID: requestUser(r).ID, Valid: true},
+		SortBy:    "published",
+		RowLimit:  webPageSize + 1,
+		RowOffset: int32((pageNumber - 1) * webPageSize),
+	}
+	page := webPage{Title: "All posts", Feed: query.Get("feed")}
+	if page.Feed != "" {
+		params.Feed = sql.NullString{String: page.Feed, Valid: true}
+	}
+	var data postsData
+	for _, filter := range []struct{ show, label string }{{"", "All"}, {"unread", "Unread"}, {"starred", "Starred"}} {
+		link := url.Values{}
+		if page.Feed != "" {
+			link.Set("feed", page.Feed)
+		}
+		if filter.show != "" {
+			link.Set("show", filter.show)
+		}
+		data.Filters = append(data.Filters, webLink{
+			Label:   filter.label,
+			URL:     "/posts?" + link.Encode(),
+			Current: filter.show == query.Get("show"),
+		})
+	}
+	switch query.Get("show") {
+	case "unread":
+		params.Read = sql.NullBool{Bool: false, Valid: true}
+	case "starred":
+		params.Starred = sql.NullBool{Bool: true, Valid: true}
+	}
+
+	if err := s.sidebar(r, &page); err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	for _, feed := range page.Feeds {
+		if feed.Url == page.Feed {
+			page.Title = feed.Name
+		}
+	}
+	posts, err := s.db.GetPostsForUser(r.Context(), params)
+	if err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	more := len(posts) > webPageSize
+	if more {
+		posts = posts[:webPageSize]
+	}
+	for _, post := range posts {
+		data.Posts = append(data.Posts, webPostOf(post))
+	}
+	pageURL := func(n int) string {
+		query.Set("page", strconv.Itoa(n))
+		return "/posts?" + query.Encode()
+	}
+	if pageNumber > 1 {
+		data.PrevURL = pageURL(pageNumber - 1)
+	}
+	if more {
+		data.NextURL = pageURL(pageNumber + 1)
+	}
+	page.Data = data
+	renderPage(w, r, http.StatusOK, "posts", page)
+}
+
+// webPostByPath loads the post in the path, answering for itself if it
+// can't.
+func (s *Server) webPostByPath(w http.ResponseWriter, r *http.Request) (database.GetPostForUserRow, bool) {
+	id, err := uuid.Parse(r.PathValue("post"))
+	if err != nil {
+		webError(w, r, http.StatusNotFound, "There's no such post.")
+		return database.GetPostForUserRow{}, false
+	}
+	post, err := s.db.GetPostForUser(r.Context(), database.GetPostForUserParams{
+		UserID: requestUser(r).ID,
+		ID:     id,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		webError(w, r, http.StatusNotFound, "There's no such post.")
+		return post, false
+	}
+	if err != nil {
+		webInternalError(w, r, err)
+		return post, false
+	}
+	return post, true
+}
+
+// handlePostPage shows one post and marks it read, like show does.
+func (s *Server) handlePostPage(w http.ResponseWriter, r *http.Request) {
+	post, ok := s.webPostByPath(w, r)
+	if !ok {
+		return
+	}
+	if !post.IsRead {
+		err := s.db.MarkPostRead(r.Context(), database.MarkPostReadParams{
+			UserID: requestUser(r).ID,
+			PostID: post.ID,
+			ReadAt: time.Now(),
+		})
+		if err != nil {
+			webInternalError(w, r, err)
+			return
+		}
+		post.IsRead = true
+	}
+	shown := webPostOf(database.GetPostsForUserRow(post))
+	page := webPage{Title: shown.Title, Feed: post.FeedUrl, Data: shown}
+	if err := s.sidebar(r, &page); err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	renderPage(w, r, http.StatusOK, "post", page)
+}
+
+// handleWebSetRead and handleWebSetStarred set a post's state to the form's
+// "on" value and go back to the post.
+func (s *Server) handleWebSetRead(w http.ResponseWriter, r *http.Request) {
+	post, ok := s.webPostByPath(w, r)
+	if !ok {
+		return
+	}
+	user := requestUser(r)
+	var err error
+	if r.PostFormValue("on") == "1" {
+		err = s.db.MarkPostRead(r.Context(), database.MarkPostReadParams{UserID: user.ID, PostID: post.ID, ReadAt: time.Now()})
+	} else {
+		err = s.db.MarkPostUnread(r.Context(), database.MarkPostUnreadParams{UserID: user.ID, PostID: post.ID})
+	}
+	if err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, localPath(r.PostFormValue("next"), "/posts"), http.StatusSeeOther)
+}
+
+func (s *Server) handleWebSetStarred(w http.ResponseWriter, r *http.Request) {
+	post, ok := s.webPostByPath(w, r)
+	if !ok {
+		return
+	}
+	user := requestUser(r)
+	var err error
+	if r.PostFormValue("on") == "1" {
+		err = s.db.StarPost(r.Context(), database.StarPostParams{UserID: user.ID, PostID: post.ID, StarredAt: time.Now()})
+	} else {
+		err = s.db.UnstarPost(r.Context(), database.UnstarPostParams{UserID: user.ID, PostID: post.ID})
+	}
+	if err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/posts/"+post.ID.String(), http.StatusSeeOther)
+}
+
+type feedsData struct {
+	// Others are the feeds the user doesn't follow.
+	Others []database.Feed
+	Name   string
+	URL    string
+}
+
+// feedsPage shows the feeds page with the addfeed form filled in with form.
+func (s *Server) feedsPage(w http.ResponseWriter, r *http.Request, status int, form feedsData, msg string) {
+	page := webPage{Title: "Feeds", Error: msg}
+	if err := s.sidebar(r, &page); err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	feeds, err := s.db.GetFeeds(r.Context())
+	if err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	followed := make(map[uuid.UUID]bool, len(page.Feeds))
+	for _, feed := range page.Feeds {
+		followed[feed.ID] = true
+	}
+	for _, feed := range feeds {
+		if !followed[feed.ID] && !feed.DeactivatedAt.Valid {
+			form.Others = append(form.Others, feed)
+		}
+	}
+	page.Data = form
+	renderPage(w, r, status, "feeds", page)
+}
+
+func (s *Server) handleFeedsPage(w http.ResponseWriter, r *http.Request) {
+	s.feedsPage(w, r, http.StatusOK, feedsData{}, "")
+}
+
+// handleWebAddFeed adds a feed and follows it, like addfeed.
+func (s *Server) handleWebAddFeed(w http.ResponseWriter, r *http.Request) {
+	form := feedsData{
+		Name: strings.TrimSpace(r.PostFormValue("name")),
+		URL:  strings.TrimSpace(r.PostFormValue("url")),
+	}
+	if form.Name == "" {
+		s.feedsPage(w, r, http.StatusBadRequest, form, "The feed needs a name.")
+		return
+	}
+	if feedURL, err := url.Parse(form.URL); err != nil || (feedURL.Scheme != "http" && feedURL.Scheme != "https") || feedURL.Host == "" {
+		s.feedsPage(w, r, http.StatusBadRequest, form, "The URL must be an absolute http or https URL.")
+		return
+	}
+	user := requestUser(r)
+	feed, err := s.db.CreateFeed(r.Context(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      form.Name,
+		Url:       form.URL,
+		UserID:    uuid.NullUUID{UUID: user.ID, Valid: true},
+	})
+	if isUniqueViolation(err) {
+		s.feedsPage(w, r, http.StatusConflict, form, "That feed already exists, follow it below instead.")
+		return
+	}
+	if err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	if _, err := s.follow(r, user, feed); err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/feeds", http.StatusSeeOther)
+}
+
+func (s *Server) handleWebFollow(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PostFormValue("feed"))
+	if err != nil {
+		webError(w, r, http.StatusBadRequest, "There's no such feed.")
+		return
+	}
+	feed, err := s.db.GetFeed(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		webError(w, r, http.StatusNotFound, "There's no such feed.")
+		return
+	}
+	if err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	// following twice is no error here; the page was just out of date
+	if _, err := s.follow(r, requestUser(r), feed); err != nil && !isUniqueViolation(err) {
+		webInternalError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/feeds", http.StatusSeeOther)
+}
+
+func (s *Server) handleWebUnfollow(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("feed"))
+	if err != nil {
+		webError(w, r, http.StatusBadRequest, "There's no such feed.")
+		return
+	}
+	_, err = s.db.DeleteFeedFollow(r.Context(), database.DeleteFeedFollowParams{
+		UserID: uuid.NullUUID{UUID: requestUser(r).ID, Valid: true},
+		FeedID: uuid.NullUUID{UUID: id, Valid: true},
+	})
+	if err != nil {
+		webInternalError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/feeds", http.StatusSeeOther)
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/posts?page=2", "/posts?page=2"},
+		{"/feeds", "/feeds"},
+		{"", "/fallback"},
+		{"posts", "/fallback"},
+		{"https://evil.example/", "/fallback"},
+		{"//evil.example/", "/fallback"},
+		{`/\evil.example/`, "/fallback"},
+		{"/\t/evil.example/", "/fallback"},
+		{"/\n/evil.example/", "/fallback"},
+		{"/posts\r\nSet-Cookie: x=1", "/fallback"},
+		{"javascript:alert(1)", "/fallback"},
+	}
+	for _, tt := range tests {
+		if got := localPath(tt.path, "/fallback"); got != tt.want {
+			t.Errorf("localPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRequireSession(t *testing.T) {
+	s, alice, bob := newTestServer(t)
+	session := func(token string) http.Header {
+		return http.Header{
+			"Cookie":       {(&http.Cookie{Name: sessionCookie, Value: token}).String()},
+			"Content-Type": {"application/x-www-form-urlencoded"},
+		}
+	}
+	form := func(csrf string) string {
+		return url.Values{"csrf": {csrf}, "on": {"1"}}.Encode()
+	}
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header http.Header
+		body   string
+		want   int
+	}{
+		{
+			name:   "page without a session",
+			method: http.MethodGet,
+			path:   "/posts/" + alice.post.String(),
+			want:   http.StatusSeeOther,
+		},
+		{
+			name:   "form without a session",
+			method: http.MethodPost,
+			path:   "/posts/" + alice.post.String() + "/read",
+			header: http.Header{"Content-Type": {"application/x-www-form-urlencoded"}},
+			body:   form(csrfToken(alice.token)),
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "form with a revoked session",
+			method: http.MethodPost,
+			path:   "/posts/" + alice.post.String() + "/read",
+			header: session("gator_revoked_token"),
+			body:   form(csrfToken("gator_revoked_token")),
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "form without a CSRF token",
+			method: http.MethodPost,
+			path:   "/posts/" + alice.post.String() + "/read",
+			header: session(alice.token),
+			body:   url.Values{"on": {"1"}}.Encode(),
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "form with another session's CSRF token",
+			method: http.MethodPost,
+			path:   "/posts/" + alice.post.String() + "/read",
+			header: session(alice.token),
+			body:   form(csrfToken(bob.token)),
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "form with the CSRF token",
+			method: http.MethodPost,
+			path:   "/posts/" + alice.post.String() + "/read",
+			header: session(alice.token),
+			body:   form(csrfToken(alice.token)),
+			want:   http.StatusSeeOther,
+		},
+		{
+			name:   "form for another user's post",
+			method: http.MethodPost,
+			path:   "/posts/" + bob.post.String() + "/read",
+			header: session(alice.token),
+			body:   form(csrfToken(alice.token)),
+			want:   http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := serve(s, tt.method, tt.path, strings.NewReader(tt.body), tt.header)
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.StatusCode, tt.want, readBody(t, resp))
+			}
+		})
+	}
+}
+
+func TestRequireSessionRedirect(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	resp := serve(s, http.MethodGet, "/posts?page=2", nil, nil)
+	want := "/login?next=" + url.QueryEscape("/posts?page=2")
+	if got := resp.Header.Get("Location"); got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+}
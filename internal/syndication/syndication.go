@@ -0,0 +1,244 @@
+// Package syndication writes feeds as Atom or RSS documents.
+package syndication
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	Atom = "atom"
+	RSS  = "rss"
+
+	generator    = "gator"
+	generatorURI = "https://github.com/jamistoso/gator"
+)
+
+// Formats lists the document formats Write accepts.
+var Formats = []string{Atom, RSS}
+
+// Feed is what both formats are written from.
+type Feed struct {
+	// ID is a URI naming the feed that never changes, e.g. a urn:uuid.
+	ID       string
+	Title    string
+	Subtitle string
+	Author   string
+	// Link is the address the document is published at, if it's known.
+	Link    string
+	Updated time.Time
+	Entries []Entry
+}
+
+type Entry struct {
+	ID    string
+	Title string
+	Link  string
+	// Published is zero when the entry didn't say.
+	Published   time.Time
+	Updated     time.Time
+	ContentHTML string
+	// SourceTitle and SourceURL name the feed the entry was taken from.
+	SourceTitle string
+	SourceURL   string
+}
+
+func ValidFormat(format string) bool {
+	for _, valid := range Formats {
+		if format == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentType is the media type of documents in format.
+func ContentType(format string) string {
+	if format == RSS {
+		return "application/rss+xml"
+	}
+	return "application/atom+xml"
+}
+
+// Write writes feed to w as an Atom or RSS document.
+func Write(w io.Writer, format string, feed Feed) error {
+	var doc any
+	switch format {
+	case Atom:
+		doc = atomDocument(feed)
+	case RSS:
+		doc = rssDocument(feed)
+	default:
+		return fmt.Errorf("unknown feed format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type atomFeed struct {
+	XMLName   xml.Name      `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string        `xml:"id"`
+	Title     string        `xml:"title"`
+	Subtitle  string        `xml:"subtitle,omitempty"`
+	Updated   string        `xml:"updated"`
+	Author    atomPerson    `xml:"author"`
+	Links     []atomLink    `xml:"link"`
+	Generator atomGenerator `xml:"generator"`
+	Entries   []atomEntry   `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomGenerator struct {
+	URI  string `xml:"uri,attr"`
+	Name string `xml:",chardata"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     atomText    `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Links     []atomLink  `xml:"link"`
+	Content   *atomText   `xml:"content"`
+	Source    *atomSource `xml:"source"`
+}
+
+type atomSource struct {
+	Title string     `xml:"title"`
+	Links []atomLink `xml:"link"`
+}
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func atomDocument(feed Feed) atomFeed {
+	doc := atomFeed{
+		ID:        feed.ID,
+		Title:     feed.Title,
+		Subtitle:  feed.Subtitle,
+		Updated:   atomTime(feed.Updated),
+		Author:    atomPerson{Name: feed.Author},
+		Generator: atomGenerator{URI: generatorURI, Name: generator},
+	}
+	if feed.Link != "" {
+		doc.Links = append(doc.Links, atomLink{Rel: "self", Type: ContentType(Atom), Href: feed.Link})
+	}
+	for _, entry := range feed.Entries {
+		e := atomEntry{
+			ID:      entry.ID,
+			Title:   atomText{Type: "text", Body: entry.Title},
+			Updated: atomTime(entry.Updated),
+			Links:   []atomLink{{Rel: "alternate", Href: entry.Link}},
+		}
+		if !entry.Published.IsZero() {
+			e.Published = atomTime(entry.Published)
+		}
+		if entry.ContentHTML != "" {
+			e.Content = &atomText{Type: "html", Body: entry.ContentHTML}
+		}
+		if entry.SourceTitle != "" {
+			e.Source = &atomSource{Title: entry.SourceTitle}
+			if entry.SourceURL != "" {
+				e.Source.Links = []atomLink{{Rel: "self", Href: entry.SourceURL}}
+			}
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+	return doc
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link,omitempty"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Generator     string    `xml:"generator"`
+	SelfLink      *atomLink `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string     `xml:"title,omitempty"`
+	Link        string     `xml:"link"`
+	Description string     `xml:"description,omitempty"`
+	GUID        rssGUID    `xml:"guid"`
+	PubDate     string     `xml:"pubDate,omitempty"`
+	Source      *rssSource `xml:"source"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssSource struct {
+	URL   string `xml:"url,attr"`
+	Title string `xml:",chardata"`
+}
+
+func rssDocument(feed Feed) rssFeed {
+	doc := rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         feed.Title,
+			Link:          feed.Link,
+			Description:   feed.Subtitle,
+			LastBuildDate: feed.Updated.UTC().Format(time.RFC1123Z),
+			Generator:     generator,
+		},
+	}
+	if feed.Link != "" {
+		doc.Channel.SelfLink = &atomLink{Rel: "self", Type: ContentType(RSS), Href: feed.Link}
+	}
+	for _, entry := range feed.Entries {
+		item := rssItem{
+			Title:       entry.Title,
+			Link:        entry.Link,
+			Description: entry.ContentHTML,
+			GUID:        rssGUID{Value: entry.ID},
+		}
+		if !entry.Published.IsZero() {
+			item.PubDate = entry.Published.UTC().Format(time.RFC1123Z)
+		}
+		// RSS sources need the feed's URL
+		if entry.SourceURL != "" {
+			item.Source = &rssSource{URL: entry.SourceURL, Title: entry.SourceTitle}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return doc
+}
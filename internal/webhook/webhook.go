@@ -0,0 +1,143 @@
+// Package webhook sends new posts to the URLs users registered for them,
+// signed so receivers can tell the requests came from gator.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// EventNewPosts is the only event there is so far.
+	EventNewPosts = "posts.new"
+
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body,
+	// keyed with the webhook's secret.
+	SignatureHeader = "X-Gator-Signature"
+	EventHeader     = "X-Gator-Event"
+	// DeliveryHeader is the same for every attempt at one delivery, so
+	// receivers can drop repeats.
+	DeliveryHeader = "X-Gator-Delivery"
+
+	secretBytes    = 32
+	attemptTimeout = 10 * time.Second
+)
+
+// Backoff is how long to wait before each retry; a delivery is given up
+// after len(Backoff)+1 attempts.
+var Backoff = []time.Duration{time.Second, 5 * time.Second, 25 * time.Second}
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	Event string `json:"event"`
+	Feed  Feed   `json:"feed"`
+	Posts []Post `json:"posts"`
+}
+
+type Feed struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type Post struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	// Published is nil when the post didn't say.
+	Published   *time.Time `json:"published,omitempty"`
+	ContentHTML string     `json:"content_html"`
+	ContentText string     `json:"content_text"`
+}
+
+// Result is how a delivery went. StatusCode is 0 when no response came
+// back, and Err is nil once the receiver answered with a 2xx.
+type Result struct {
+	Attempts   int
+	StatusCode int
+	Err        error
+}
+
+// NewSecret makes the key a webhook's deliveries are signed with.
+func NewSecret() (string, error) {
+	random := make([]byte, secretBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
+
+// ValidURL reports why address can't receive deliveries, if it can't.
+func ValidURL(address string) error {
+	u, err := url.Parse(address)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook url must be an absolute http or https url: %s", address)
+	}
+	return nil
+}
+
+// Sign returns the value of SignatureHeader for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver posts body to address, retrying after Backoff on network errors,
+// 429 and 5xx responses. Other responses are final. Cancelling ctx stops
+// the retries.
+func Deliver(ctx context.Context, client *http.Client, address, secret, delivery string, body []byte) Result {
+	var result Result
+	for {
+		result.Attempts++
+		var retry bool
+		result.StatusCode, retry, result.Err = attempt(ctx, client, address, secret, delivery, body)
+		if !retry || result.Attempts > len(Backoff) {
+			return result
+		}
+		select {
+		case <-ctx.Done():
+			return result
+		case <-time.After(Backoff[result.Attempts-1]):
+		}
+	}
+}
+
+func attempt(ctx context.Context, client *http.Client, address, secret, delivery string, body []byte) (int, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, attemptTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gator")
+	req.Header.Set(EventHeader, EventNewPosts)
+	req.Header.Set(DeliveryHeader, delivery)
+	req.Header.Set(SignatureHeader, Sign(secret, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, !errors.Is(err, context.Canceled), err
+	}
+	defer resp.Body.Close()
+	// reading the body lets the connection be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return resp.StatusCode, false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return resp.StatusCode, retry, fmt.Errorf("unexpected status: %s", resp.Status)
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// the HMAC-SHA256 example from Wikipedia's HMAC article
+	got := Sign("key", []byte("The quick brown fox jumps over the lazy dog"))
+	want := "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+}
+
+// fastBackoff makes retries immediate for the rest of the test.
+func fastBackoff(t *testing.T) {
+	saved := Backoff
+	Backoff = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
+	t.Cleanup(func() { Backoff = saved })
+}
+
+func TestDeliverHeaders(t *testing.T) {
+	const (
+		secret   = "s3cret"
+		delivery = "delivery-1"
+	)
+	body := []byte(`{"event":"posts.new"}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method != http.MethodPost:
+			t.Errorf("method = %s, want POST", r.Method)
+		case string(got) != string(body):
+			t.Errorf("body = %s, want %s", got, body)
+		case r.Header.Get(SignatureHeader) != Sign(secret, body):
+			t.Errorf("%s = %s, want %s", SignatureHeader, r.Header.Get(SignatureHeader), Sign(secret, body))
+		case r.Header.Get(EventHeader) != EventNewPosts:
+			t.Errorf("%s = %s, want %s", EventHeader, r.Header.Get(EventHeader), EventNewPosts)
+		case r.Header.Get(DeliveryHeader) != delivery:
+			t.Errorf("%s = %s, want %s", DeliveryHeader, r.Header.Get(DeliveryHeader), delivery)
+		case r.Header.Get("Content-Type") != "application/json":
+			t.Errorf("Content-Type = %s, want application/json", r.Header.Get("Content-Type"))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	result := Deliver(context.Background(), server.Client(), server.URL, secret, delivery, body)
+	if result.Err != nil || result.Attempts != 1 || result.StatusCode != http.StatusNoContent {
+		t.Errorf("Deliver() = %+v, want one successful attempt", result)
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	fastBackoff(t)
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int
+		wantStatus   int
+		wantErr      bool
+	}{
+		{"success", []int{200}, 1, 200, false},
+		{"client error is final", []int{400}, 1, 400, true},
+		{"not found is final", []int{404}, 1, 404, true},
+		{"gone is final", []int{410}, 1, 410, true},
+		{"redirect is final", []int{302}, 1, 302, true},
+		{"server error retried", []int{500, 502, 200}, 3, 200, false},
+		{"too many requests retried", []int{429, 200}, 2, 200, false},
+		{"client error after a retry", []int{503, 403}, 2, 403, true},
+		{"gives up", []int{500, 500, 500, 500, 500}, 4, 500, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1))
+				if n > len(tt.statuses) {
+					t.Errorf("attempt %d, want at most %d", n, len(tt.statuses))
+					n = len(tt.statuses)
+				}
+				if tt.statuses[n-1] == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer server.Close()
+			client := server.Client()
+			client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+			result := Deliver(context.Background(), client, server.URL, "secret", "delivery", []byte("{}"))
+			if result.Attempts != tt.wantAttempts || result.StatusCode != tt.wantStatus || (result.Err != nil) != tt.wantErr {
+				t.Errorf("Deliver() = %+v, want %d attempts, status %d, error %t", result, tt.wantAttempts, tt.wantStatus, tt.wantErr)
+			}
+			if got := int(calls.Load()); got != tt.wantAttempts {
+				t.Errorf("server saw %d requests, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestDeliverCancelled(t *testing.T) {
+	saved := Backoff
+	Backoff = []time.Duration{time.Hour}
+	t.Cleanup(func() { Backoff = saved })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	result := Deliver(ctx, server.Client(), server.URL, "secret", "delivery", []byte("{}"))
+	if result.Attempts != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("Deliver() = %+v after %s, want it to stop waiting once cancelled", result, time.Since(start))
+	}
+}
+
+func TestValidURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://example.com/hook", true},
+		{"http://localhost:8080/hook", true},
+		{"ftp://example.com/", false},
+		{"example.com/hook", false},
+		{"https:///path", false},
+		{"javascript:alert(1)", false},
+	}
+	for _, tt := range tests {
+		if err := ValidURL(tt.url); (err == nil) != tt.valid {
+			t.Errorf("ValidURL(%q) = %v, want valid %t", tt.url, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// notify shows a desktop notification with notify-send, or osascript on
+// macOS.
+func notify(title, body string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		script := "display notification " + appleScriptString(body) + " with title " + appleScriptString(title)
+		return exec.Command("osascript", "-e", script).Run()
+	case "windows":
+		return errors.New("desktop notifications aren't supported on Windows")
+	default:
+		return exec.Command("notify-send", "--app-name=gator", title, body).Run()
+	}
+}
+
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
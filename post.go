@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/jamistoso/gator/internal/htmltext"
+)
+
+const (
+	// shortPostIDLength is how many hex digits of a post's id browse shows.
+	// Being a prefix of the id, it never changes.
+	shortPostIDLength = 8
+	// minPostIDLength is the shortest prefix open and show accept.
+	minPostIDLength = 4
+)
+
+func shortPostID(id uuid.UUID) string {
+	return id.String()[:shortPostIDLength]
+}
+
+// postContent picks the HTML to render for a post. Posts stored before
+// sanitizing was added only have a description.
+func postContent(description, contentHTML sql.NullString) string {
+	if contentHTML.Valid {
+		return contentHTML.String
+	}
+	return description.String
+}
+
+// findPost looks a post up among the user's followed feeds by its id or a
+// unique prefix of it, as shown by browse.
+func findPost(ctx context.Context, s *state, user database.User, id string) (database.GetPostsByIDRangeRow, error) {
+	digits := strings.ToLower(strings.ReplaceAll(id, "-", ""))
+	if len(digits) < minPostIDLength || len(digits) > 32 || strings.Trim(digits, "0123456789abcdef") != "" {
+		return database.GetPostsByIDRangeRow{}, newUsageError("invalid post id %q: use the id shown by browse", id)
+	}
+	// every id starting with the prefix sorts between these two
+	low, err := uuid.Parse(digits + strings.Repeat("0", 32-len(digits)))
+	if err != nil {
+		return database.GetPostsByIDRangeRow{}, err
+	}
+	high, err := uuid.Parse(digits + strings.Repeat("f", 32-len(digits)))
+	if err != nil {
+		return database.GetPostsByIDRangeRow{}, err
+	}
+
+	posts, err := s.db.GetPostsByIDRange(ctx, database.GetPostsByIDRangeParams{
+		UserID: uuid.NullUUID{UUID: user.ID, Valid: true},
+		LowID:  low,
+		HighID: high,
+	})
+	if err != nil {
+		return database.GetPostsByIDRangeRow{}, err
+	}
+	switch len(posts) {
+	case 0:
+		return database.GetPostsByIDRangeRow{}, fmt.Errorf("post not found: %s", id)
+	case 1:
+		return posts[0], nil
+	}
+	return database.GetPostsByIDRangeRow{}, fmt.Errorf("post id %s is ambiguous, give more of it", id)
+}
+
+func markPostRead(ctx context.Context, s *state, user database.User, postID uuid.UUID) error {
+	return s.db.MarkPostRead(ctx, database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: postID,
+		ReadAt: time.Now(),
+	})
+}
+
+func handlerOpen(s *state, cmd command, currentUser database.User) error {
+	ctx := context.Background()
+	post, err := findPost(ctx, s, currentUser, cmd.arguments[0])
+	if err != nil {
+		return err
+	}
+	if err := openInBrowser(post.Url); err != nil {
+		return fmt.Errorf("couldn't open %s: %w", post.Url, err)
+	}
+	return markPostRead(ctx, s, currentUser, post.ID)
+}
+
+func handlerShow(s *state, cmd command, currentUser database.User) error {
+	ctx := context.Background()
+	post, err := findPost(ctx, s, currentUser, cmd.arguments[0])
+	if err != nil {
+		return err
+	}
+	color, err := useColor(cmd.stringFlag("color"))
+	if err != nil {
+		return err
+	}
+
+	title := htmltext.Line(post.Title.String)
+	if color {
+		title = "\x1b[1m" + title + "\x1b[0m"
+	}
+	var b strings.Builder
+	fmt.Fprintln(&b, title)
+	fmt.Fprintf(&b, "%s · %s\n", post.FeedName, post.PublishedAt.Time.Format(time.RFC1123))
+	fmt.Fprintln(&b, post.Url)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, htmltext.Render(postContent(post.Description, post.ContentHtml), htmltext.Options{
+		Width: terminalWidth(),
+		Color: color,
+	}))
+	if err := page(os.Stdout, b.String()); err != nil {
+		return err
+	}
+	return markPostRead(ctx, s, currentUser, post.ID)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/jamistoso/gator/internal/htmltext"
+	"github.com/jamistoso/gator/internal/output"
+	"github.com/jamistoso/gator/internal/rules"
+)
+
+const (
+	ruleUsage = "usage: gator rule add <pattern> | list | remove <id> | test <id|pattern>"
+	// defaultRuleTestLimit is how many recent posts rule test looks at.
+	defaultRuleTestLimit = 100
+)
+
+// notifyFailed stops a missing notifier from being reported for every post.
+var notifyFailed bool
+
+func handlerRule(s *state, cmd command, currentUser database.User) error {
+	args := cmd.arguments[1:]
+	switch cmd.arguments[0] {
+	case "add":
+		if len(args) != 1 {
+			return newUsageError("rule add: wrong number of arguments\n%s", ruleUsage)
+		}
+		return handlerRuleAdd(s, cmd, args[0], currentUser)
+	case "list":
+		if len(args) != 0 {
+			return newUsageError("rule list: wrong number of arguments\n%s", ruleUsage)
+		}
+		return handlerRuleList(s, currentUser)
+	case "remove":
+		if len(args) != 1 {
+			return newUsageError("rule remove: wrong number of arguments\n%s", ruleUsage)
+		}
+		return handlerRuleRemove(s, args[0], currentUser)
+	case "test":
+		if len(args) != 1 {
+			return newUsageError("rule test: wrong number of arguments\n%s", ruleUsage)
+		}
+		return handlerRuleTest(s, cmd, args[0], currentUser)
+	default:
+		return newUsageError("rule: unknown subcommand %s\n%s", cmd.arguments[0], ruleUsage)
+	}
+}
+
+// ruleFromFlags builds a rule for pattern out of the add and test flags,
+// checking that it compiles.
+func ruleFromFlags(s *state, cmd command, pattern string, currentUser database.User) (database.CreateRuleParams, error) {
+	params := database.CreateRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    currentUser.ID,
+		Field:     cmd.stringFlag("field"),
+		Pattern:   pattern,
+		IsRegex:   cmd.boolFlag("regex"),
+		Action:    cmd.stringFlag("action"),
+	}
+	if _, err := rules.Compile(params.Field, params.Pattern, params.IsRegex); err != nil {
+		return params, newUsageError("%s: %v", cmd.name, err)
+	}
+	if feedURL := cmd.stringFlag("feed"); feedURL != "" {
+		feed, err := followedFeed(context.Background(), s, currentUser, feedURL)
+		if err != nil {
+			return params, err
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	return params, nil
+}
+
+func handlerRuleAdd(s *state, cmd command, pattern string, currentUser database.User) error {
+	params, err := ruleFromFlags(s, cmd, pattern, currentUser)
+	if err != nil {
+		return err
+	}
+	if !rules.ValidAction(params.Action) {
+		return newUsageError("rule add: --action must be one of %s", strings.Join(rules.Actions, ", "))
+	}
+	tag := strings.TrimSpace(cmd.stringFlag("tag"))
+	switch {
+	case params.Action == rules.Tag && tag == "":
+		return newUsageError("rule add: the tag action needs --tag")
+	case params.Action != rules.Tag && tag != "":
+		return newUsageError("rule add: --tag only goes with --action tag")
+	case tag != "":
+		params.Tag = sql.NullString{String: tag, Valid: true}
+	}
+
+	rule, err := s.db.CreateRule(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Added rule %s: %s\n", rule.ID, describeRule(rule, ""))
+	return nil
+}
+
+func handlerRuleList(s *state, currentUser database.User) error {
+	dbRules, err := s.db.GetRulesForUser(context.Background(), currentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	if s.output != "" {
+		table := output.Table{Columns: []string{"id", "action", "tag", "field", "pattern", "regex", "feed", "created_at"}}
+		for _, rule := range dbRules {
+			table.Rows = append(table.Rows, []any{
+				rule.ID, rule.Action, rule.Tag, rule.Field, rule.Pattern, rule.IsRegex, rule.FeedUrl, rule.CreatedAt,
+			})
+		}
+		return output.Write(os.Stdout, s.output, table)
+	}
+
+	for _, rule := range dbRules {
+		fmt.Printf("%s %s\n", rule.ID, describeRule(database.Rule{
+			Field:   rule.Field,
+			Pattern: rule.Pattern,
+			IsRegex: rule.IsRegex,
+			Action:  rule.Action,
+			Tag:     rule.Tag,
+		}, rule.FeedUrl.String))
+	}
+	return nil
+}
+
+// describeRule says what a rule does, e.g. `star posts whose title
+// contains "go"`.
+func describeRule(rule database.Rule, feedURL string) string {
+	action := rule.Action
+	if rule.Action == rules.Tag {
+		action = fmt.Sprintf("tag %q", rule.Tag.String)
+	}
+	field := "title, content, author or category"
+	if rule.Field != rules.Any {
+		field = rule.Field
+	}
+	match := "contains"
+	if rule.IsRegex {
+		match = "matches"
+	}
+	out := fmt.Sprintf("%s posts whose %s %s %q", action, field, match, rule.Pattern)
+	if feedURL != "" {
+		out += " from " + feedURL
+	}
+	return out
+}
+
+func handlerRuleRemove(s *state, id string, currentUser database.User) error {
+	ruleID, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("rule not found: %s", id)
+	}
+	removed, err := s.db.DeleteRule(context.Background(), database.DeleteRuleParams{
+		UserID: currentUser.ID,
+		ID:     ruleID,
+	})
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return fmt.Errorf("rule not found: %s", id)
+	}
+	fmt.Printf("Rule %s removed\n", id)
+	return nil
+}
+
+// handlerRuleTest lists which of the user's recent posts a saved rule, or
+// a pattern with the add flags, would match, without acting on them.
+func handlerRuleTest(s *state, cmd command, arg string, currentUser database.User) error {
+	var rule database.Rule
+	if ruleID, err := uuid.Parse(arg); err == nil {
+		rule, err = s.db.GetRule(context.Background(), database.GetRuleParams{UserID: currentUser.ID, ID: ruleID})
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("rule not found: %s", arg)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		params, err := ruleFromFlags(s, cmd, arg, currentUser)
+		if err != nil {
+			return err
+		}
+		rule = database.Rule{FeedID: params.FeedID, Field: params.Field, Pattern: params.Pattern, IsRegex: params.IsRegex}
+	}
+	matcher, err := rules.Compile(rule.Field, rule.Pattern, rule.IsRegex)
+	if err != nil {
+		return err
+	}
+
+	limit := cmd.intFlag("limit")
+	if limit < 1 {
+		return newUsageError("rule test: --limit must be at least 1")
+	}
+	params := database.GetPostsForUserParams{
+		UserID:        uuid.NullUUID{UUID: currentUser.ID, Valid: true},
+		IncludeHidden: true,
+		SortBy:        "fetched",
+		RowLimit:      int32(limit),
+	}
+	if rule.FeedID.Valid {
+		feed, err := s.db.GetFeed(context.Background(), rule.FeedID.UUID)
+		if err != nil {
+			return err
+		}
+		params.Feed = sql.NullString{String: feed.Url, Valid: true}
+	}
+	posts, err := s.db.GetPostsForUser(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	matched := 0
+	for _, post := range posts {
+		if !matcher.Match(rules.Post{
+			Title:      post.Title.String,
+			Content:    post.ContentText.String,
+			Author:     post.Author.String,
+			Categories: post.Categories,
+		}) {
+			continue
+		}
+		matched++
+		fmt.Printf("%s (%s)\n", htmltext.Line(post.Title.String), post.FeedName)
+	}
+	fmt.Printf("%d of the %d most recently fetched posts match\n", matched, len(posts))
+	return nil
+}
+
+// applyRules runs the rules of the feed's followers over its new posts.
+func applyRules(ctx context.Context, s *state, dbFeed database.Feed, posts []database.UpsertPostRow) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	dbRules, err := s.db.GetRulesForFeed(ctx, uuid.NullUUID{UUID: dbFeed.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+	notifyUser, err := notifyUserID(ctx, s, dbRules)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, rule := range dbRules {
+		// notifications pop up wherever agg runs, so only its own user gets them
+		if rule.Action == rules.Notify && rule.UserID != notifyUser {
+			continue
+		}
+		matcher, err := rules.Compile(rule.Field, rule.Pattern, rule.IsRegex)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.ID, err))
+			continue
+		}
+		for _, post := range posts {
+			matches := matcher.Match(rules.Post{
+				Title:      post.Title.String,
+				Content:    post.ContentText.String,
+				Author:     post.Author.String,
+				Categories: post.Categories,
+			})
+			if !matches {
+				continue
+			}
+			if err := applyRule(ctx, s, rule, dbFeed, post); err != nil {
+				errs = append(errs, fmt.Errorf("rule %s: %w", rule.ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notifyUserID is the user whose notify rules apply: the one gator is
+// logged in as. uuid.Nil, matching nobody, when there is none or no rule
+// notifies.
+func notifyUserID(ctx context.Context, s *state, dbRules []database.Rule) (uuid.UUID, error) {
+	if s.cfg.Current_user_name == "" {
+		return uuid.Nil, nil
+	}
+	for _, rule := range dbRules {
+		if rule.Action != rules.Notify {
+			continue
+		}
+		user, err := s.db.GetUser(ctx, s.cfg.Current_user_name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, nil
+		}
+		return user.ID, err
+	}
+	return uuid.Nil, nil
+}
+
+func applyRule(ctx context.Context, s *state, rule database.Rule, dbFeed database.Feed, post database.UpsertPostRow) error {
+	now := time.Now()
+	switch rule.Action {
+	case rules.Hide:
+		err := s.db.HidePost(ctx, database.HidePostParams{UserID: rule.UserID, PostID: post.ID, HiddenAt: now})
+		if err != nil {
+			return err
+		}
+		// hidden posts shouldn't count as unread either
+		return s.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: rule.UserID, PostID: post.ID, ReadAt: now})
+	case rules.Read:
+		return s.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: rule.UserID, PostID: post.ID, ReadAt: now})
+	case rules.Star:
+		return s.db.StarPost(ctx, database.StarPostParams{UserID: rule.UserID, PostID: post.ID, StarredAt: now})
+	case rules.Tag:
+		return s.db.TagPost(ctx, database.TagPostParams{UserID: rule.UserID, PostID: post.ID, Tag: rule.Tag.String, TaggedAt: now})
+	case rules.Notify:
+		title := htmltext.Line(post.Title.String)
+		fmt.Printf("Rule %s matched \"%s\" in \"%s\": %s\n", rule.ID, title, dbFeed.Name, post.Url)
+		if notifyFailed {
+			return nil
+		}
+		if err := notify(dbFeed.Name, title); err != nil {
+			notifyFailed = true
+			return fmt.Errorf("desktop notification failed, only printing matches from now on: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q", rule.Action)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/database"
+)
+
+// scheduleFeed records when a feed is next due. The delay is the feed's
+// polling interval (manual override or learned from its posting history),
+// stretched further if the publisher asked us to back off, though never past
+// the configured maximum. It also runs for failed fetches so Retry-After is
+// honoured.
+func scheduleFeed(ctx context.Context, s *state, dbFeed database.Feed, feed *RSSFeed, feedResp feedResponse) error {
+	minInterval, maxInterval, err := s.cfg.PollBounds()
+	if err != nil {
+		return err
+	}
+	stats, err := s.db.GetFeedPostingStats(ctx, uuid.NullUUID{
+		UUID:  dbFeed.ID,
+		Valid: true,
+	})
+	if err != nil {
+		return fmt.Errorf("error reading posting history for %s: %w", dbFeed.Url, err)
+	}
+
+	learned := adaptiveInterval(stats, minInterval, maxInterval)
+	interval := learned
+	if dbFeed.IntervalOverrideSeconds.Valid {
+		interval = time.Duration(dbFeed.IntervalOverrideSeconds.Int32) * time.Second
+	}
+
+	now := time.Now()
+	delay := nextFetchTime(feed, feedResp.header, now, interval, maxInterval).Sub(now)
+	err = s.db.ScheduleFeedFetch(ctx, database.ScheduleFeedFetchParams{
+		DelaySeconds: durationSeconds(delay),
+		PollIntervalSeconds: sql.NullInt32{
+			Int32: durationSeconds(learned),
+			Valid: true,
+		},
+		ID: dbFeed.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("error scheduling feed %s: %w", dbFeed.Url, err)
+	}
+	return nil
+}
+
+// adaptiveInterval polls at twice a feed's recent posting rate, clamped to
+// the configured bounds. Feeds without enough history are polled as often
+// as allowed until we learn more about them.
+func adaptiveInterval(stats database.GetFeedPostingStatsRow, minInterval, maxInterval time.Duration) time.Duration {
+	if stats.PostCount < 2 || stats.AvgGapSeconds <= 0 {
+		return minInterval
+	}
+	interval := time.Duration(stats.AvgGapSeconds / 2 * float64(time.Second))
+	return min(max(interval, minInterval), maxInterval)
+}
+
+// durationSeconds converts d for an int32 seconds column. Longer durations
+// are clamped rather than wrapping negative, which would make a feed due on
+// every tick.
+func durationSeconds(d time.Duration) int32 {
+	return int32(min(max(d, 0), math.MaxInt32*time.Second) / time.Second)
+}
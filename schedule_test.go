@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jamistoso/gator/internal/database"
+)
+
+func TestAdaptiveInterval(t *testing.T) {
+	const (
+		minInterval = 10 * time.Minute
+		maxInterval = 24 * time.Hour
+	)
+	tests := []struct {
+		name      string
+		postCount int64
+		avgGap    time.Duration
+		want      time.Duration
+	}{
+		{"no posts", 0, 0, minInterval},
+		{"one post", 1, 0, minInterval},
+		{"no gap", 5, 0, minInterval},
+		{"twice the posting rate", 20, 4 * time.Hour, 2 * time.Hour},
+		{"frequent poster", 20, time.Minute, minInterval},
+		{"rare poster", 20, 30 * 24 * time.Hour, maxInterval},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := database.GetFeedPostingStatsRow{
+				PostCount:     tt.postCount,
+				AvgGapSeconds: tt.avgGap.Seconds(),
+			}
+			if got := adaptiveInterval(stats, minInterval, maxInterval); got != tt.want {
+				t.Errorf("adaptiveInterval() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationSeconds(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int32
+	}{
+		{0, 0},
+		{90 * time.Second, 90},
+		{1500 * time.Millisecond, 1},
+		{-time.Minute, 0},
+		{365 * 24 * time.Hour, 365 * 24 * 60 * 60},
+		{100 * 365 * 24 * time.Hour, math.MaxInt32},
+		{math.MaxInt64, math.MaxInt32},
+	}
+	for _, tt := range tests {
+		if got := durationSeconds(tt.d); got != tt.want {
+			t.Errorf("durationSeconds(%s) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jamistoso/gator/internal/server"
+)
+
+const (
+	defaultServeAddr = "localhost:8080"
+	// shutdownTimeout is how long requests in flight get to finish.
+	shutdownTimeout = 10 * time.Second
+)
+
+func handlerServe(s *state, cmd command) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", cmd.stringFlag("addr"))
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:           server.New(s.db),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(listener)
+	}()
+	fmt.Printf("Serving the web reader on http://%s/ and the API on http://%s%s/\n", listener.Addr(), listener.Addr(), server.APIPrefix)
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	fmt.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
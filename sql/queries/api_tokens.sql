@@ -0,0 +1,37 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash, token_prefix)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ReplaceAPIToken :one
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash, token_prefix)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, name) DO UPDATE
+SET created_at = EXCLUDED.created_at,
+    token_hash = EXCLUDED.token_hash,
+    token_prefix = EXCLUDED.token_prefix,
+    last_used_at = NULL
+RETURNING *;
+
+-- name: GetAPITokensForUser :many
+SELECT * FROM api_tokens
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetUserByAPIToken :one
+SELECT users.*, api_tokens.id AS token_id
+FROM api_tokens
+INNER JOIN users
+ON api_tokens.user_id = users.id
+WHERE api_tokens.token_hash = $1;
+
+-- name: TouchAPIToken :exec
+UPDATE api_tokens
+SET last_used_at = NOW()
+WHERE id = $1
+AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+
+-- name: DeleteAPIToken :execrows
+DELETE FROM api_tokens
+WHERE user_id = sqlc.arg(user_id)
+AND (id::text = sqlc.arg(token)::text OR name = sqlc.arg(token)::text);
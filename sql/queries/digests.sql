@@ -0,0 +1,34 @@
+-- name: UpsertDigest :one
+INSERT INTO digests (user_id, created_at, updated_at, email, frequency, time_zone, send_minute, weekday, next_send_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (user_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    email = EXCLUDED.email,
+    frequency = EXCLUDED.frequency,
+    time_zone = EXCLUDED.time_zone,
+    send_minute = EXCLUDED.send_minute,
+    weekday = EXCLUDED.weekday,
+    next_send_at = EXCLUDED.next_send_at
+RETURNING *;
+
+-- name: GetDigest :one
+SELECT * FROM digests
+WHERE user_id = $1;
+
+-- name: GetDueDigests :many
+SELECT digests.*, users.name AS user_name
+FROM digests
+INNER JOIN users
+ON digests.user_id = users.id
+WHERE digests.next_send_at <= sqlc.arg(now)
+ORDER BY digests.next_send_at;
+
+-- name: MarkDigestSent :exec
+UPDATE digests
+SET last_sent_at = $2,
+    next_send_at = $3
+WHERE user_id = $1;
+
+-- name: DeleteDigest :execrows
+DELETE FROM digests
+WHERE user_id = $1;
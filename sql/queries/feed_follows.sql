@@ -30,12 +30,48 @@ INNER JOIN users u
 ON ff.user_id = u.id
 INNER JOIN feeds f
 ON feed_id = f.id
-WHERE ff.user_id = $1;
+WHERE ff.user_id = $1
+ORDER BY ff.created_at, ff.id;
 
 -- name: DeleteFeedFollowForUser :exec
 DELETE FROM feed_follows ff
 WHERE ff.user_id = $1
-AND ff.feed_id = (
+AND ff.feed_id IN (
     SELECT feeds.id FROM feeds
     WHERE feeds.url = $2
-);
\ No newline at end of file
+    UNION
+    SELECT feed_aliases.feed_id FROM feed_aliases
+    WHERE feed_aliases.url = $2
+);
+
+-- name: GetFollowedFeedsWithUnreadCounts :many
+SELECT
+    feeds.id,
+    feeds.name,
+    feeds.url,
+    feeds.deactivated_at,
+    COUNT(posts.id) AS post_count,
+    COUNT(posts.id) FILTER (WHERE post_reads.post_id IS NULL) AS unread_count
+FROM feed_follows
+INNER JOIN feeds
+ON feed_follows.feed_id = feeds.id
+LEFT JOIN posts
+ON posts.feed_id = feeds.id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+GROUP BY feeds.id
+ORDER BY feeds.name;
+
+-- name: DeleteFeedFollow :execrows
+DELETE FROM feed_follows
+WHERE user_id = $1
+AND feed_id = $2;
+
+-- name: CopyFeedFollows :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+SELECT gen_random_uuid(), NOW(), NOW(), user_id, sqlc.arg(to_feed_id)
+FROM feed_follows
+WHERE feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT (user_id, feed_id) DO NOTHING;
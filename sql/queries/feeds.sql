@@ -11,19 +11,70 @@ VALUES (
 RETURNING *;
 
 -- name: GetFeeds :many
-SELECT * FROM feeds;
+SELECT * FROM feeds
+ORDER BY created_at, id;
+
+-- name: GetFollowedFeeds :many
+SELECT feeds.* FROM feeds
+INNER JOIN feed_follows
+ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.name;
 
 -- name: GetFeedFromURL :one
 SELECT * FROM feeds
-WHERE url = $1;
+WHERE url = $1
+OR id = (
+    SELECT feed_id FROM feed_aliases
+    WHERE feed_aliases.url = $1
+)
+ORDER BY (url = $1) DESC
+LIMIT 1;
 
 -- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = NOW(), updated_at = NOW()
 WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
+-- name: GetDueFeeds :many
 SELECT * 
 FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+WHERE deactivated_at IS NULL
+AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+LIMIT $1;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: CreateFeedAlias :exec
+INSERT INTO feed_aliases (id, created_at, url, feed_id)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (url) DO NOTHING;
+
+-- name: DeactivateFeed :exec
+UPDATE feeds
+SET deactivated_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: ScheduleFeedFetch :exec
+UPDATE feeds
+SET next_fetch_at = NOW() + (sqlc.arg(delay_seconds)::int * INTERVAL '1 second'),
+    poll_interval_seconds = sqlc.arg(poll_interval_seconds),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id);
+
+-- name: SetFeedIntervalOverride :exec
+UPDATE feeds
+SET interval_override_seconds = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetFeed :one
+SELECT * FROM feeds
+WHERE id = $1;
+
+-- name: GetFeedByNumber :one
+SELECT * FROM feeds
+WHERE number = $1;
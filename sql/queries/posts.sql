@@ -1,13 +1,242 @@
--- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id) 
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING *;
+-- name: UpsertPost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content_html, content_text, author, categories) 
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+ON CONFLICT (url) DO UPDATE
+SET title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    published_at = EXCLUDED.published_at,
+    content_html = EXCLUDED.content_html,
+    content_text = EXCLUDED.content_text,
+    author = EXCLUDED.author,
+    categories = EXCLUDED.categories,
+    updated_at = EXCLUDED.updated_at
+WHERE posts.title IS DISTINCT FROM EXCLUDED.title
+OR posts.description IS DISTINCT FROM EXCLUDED.description
+OR posts.published_at IS DISTINCT FROM EXCLUDED.published_at
+RETURNING *, (xmax = 0) AS inserted;
 
--- name: GetPostsForUser :many
+-- name: GetPostByURL :one
 SELECT * FROM posts
-WHERE feed_id IN (
-    SELECT id FROM feeds
-    WHERE user_id = $1
+WHERE url = $1;
+
+-- name: GetPostForUser :one
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = sqlc.arg(user_id)
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id
+        AND post_stars.user_id = sqlc.arg(user_id)
+    ) AS is_starred
+FROM posts
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id
+AND feed_follows.user_id = sqlc.arg(user_id)
+WHERE posts.id = sqlc.arg(id)
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+);
+
+-- name: GetPostNumbersForUser :many
+SELECT posts.number
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(read)::bool IS NULL OR (post_reads.read_at IS NOT NULL) = sqlc.narg(read))
+AND (sqlc.narg(starred)::bool IS NULL OR (post_stars.starred_at IS NOT NULL) = sqlc.narg(starred))
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+ORDER BY posts.number;
+
+-- name: GetPostsByIDRange :many
+SELECT
+    posts.*,
+    feeds.name AS feed_name
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND posts.id BETWEEN sqlc.arg(low_id) AND sqlc.arg(high_id)
+ORDER BY posts.id
+LIMIT 2;
+
+-- name: GetPostsForUser :many
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    (post_reads.read_at IS NOT NULL)::bool AS is_read,
+    (post_stars.starred_at IS NOT NULL)::bool AS is_starred
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(feed)::text IS NULL OR feeds.url = sqlc.narg(feed) OR feeds.name = sqlc.narg(feed))
+AND (sqlc.narg(since)::timestamp IS NULL OR posts.published_at >= sqlc.narg(since))
+AND (sqlc.narg(until)::timestamp IS NULL OR posts.published_at < sqlc.narg(until))
+AND (
+    sqlc.narg(search)::text IS NULL
+    OR posts.title ILIKE '%' || sqlc.narg(search) || '%'
+    OR posts.description ILIKE '%' || sqlc.narg(search) || '%'
+)
+AND (sqlc.narg(read)::bool IS NULL OR (post_reads.read_at IS NOT NULL) = sqlc.narg(read))
+AND (sqlc.narg(starred)::bool IS NULL OR (post_stars.starred_at IS NOT NULL) = sqlc.narg(starred))
+AND (
+    sqlc.narg(tag)::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.post_id = posts.id
+        AND post_tags.user_id = feed_follows.user_id
+        AND post_tags.tag = sqlc.narg(tag)
+    )
 )
-ORDER BY published_at DESC
-LIMIT $2;
\ No newline at end of file
+AND (
+    sqlc.arg(include_hidden)::bool
+    OR NOT EXISTS (
+        SELECT 1 FROM post_hides
+        WHERE post_hides.post_id = posts.id
+        AND post_hides.user_id = feed_follows.user_id
+    )
+)
+ORDER BY
+    CASE WHEN sqlc.arg(sort_by)::text = 'fetched' THEN posts.created_at END DESC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'feed' THEN feeds.name END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'oldest' THEN posts.published_at END ASC,
+    posts.published_at DESC NULLS LAST,
+    posts.id
+LIMIT sqlc.arg(row_limit)
+OFFSET sqlc.arg(row_offset);
+
+-- name: GetPostsForUserByNumberRange :many
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    (post_reads.read_at IS NOT NULL)::bool AS is_read,
+    (post_stars.starred_at IS NOT NULL)::bool AS is_starred
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(after_number)::bigint IS NULL OR posts.number > sqlc.narg(after_number))
+AND (sqlc.narg(before_number)::bigint IS NULL OR posts.number < sqlc.narg(before_number))
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+ORDER BY
+    CASE WHEN sqlc.narg(before_number)::bigint IS NULL THEN posts.number END ASC,
+    posts.number DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: GetPostsForUserByNumbers :many
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    (post_reads.read_at IS NOT NULL)::bool AS is_read,
+    (post_stars.starred_at IS NOT NULL)::bool AS is_starred
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars
+ON post_stars.post_id = posts.id
+AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND posts.number = ANY(sqlc.arg(numbers)::bigint[])
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+)
+ORDER BY posts.published_at DESC NULLS LAST, posts.id;
+
+-- name: GetFeedPostingStats :one
+SELECT
+    COUNT(*) AS post_count,
+    COALESCE(
+        EXTRACT(EPOCH FROM MAX(published_at) - MIN(published_at)) / NULLIF(COUNT(*) - 1, 0),
+        0
+    )::float8 AS avg_gap_seconds
+FROM (
+    SELECT published_at FROM posts
+    WHERE feed_id = $1
+    AND published_at IS NOT NULL
+    ORDER BY published_at DESC
+    LIMIT 20
+) recent_posts;
+
+-- name: CountPostsForUser :one
+SELECT COUNT(*)
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_hides
+    WHERE post_hides.post_id = posts.id
+    AND post_hides.user_id = feed_follows.user_id
+);
+
+-- name: GetUnreadPostsSince :many
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url
+FROM feed_follows
+INNER JOIN posts
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_reads
+ON post_reads.post_id = posts.id
+AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND post_reads.read_at IS NULL
+AND posts.created_at > sqlc.arg(since)
+ORDER BY feeds.name, posts.published_at DESC NULLS LAST, posts.id
+LIMIT sqlc.arg(row_limit);
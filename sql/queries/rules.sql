@@ -0,0 +1,33 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, user_id, feed_id, field, pattern, is_regex, action, tag)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetRulesForUser :many
+SELECT rules.*, feeds.url AS feed_url
+FROM rules
+LEFT JOIN feeds
+ON rules.feed_id = feeds.id
+WHERE rules.user_id = $1
+ORDER BY rules.created_at;
+
+-- name: GetRule :one
+SELECT * FROM rules
+WHERE user_id = $1
+AND id = $2;
+
+-- name: GetRulesForFeed :many
+-- Rules only apply to feeds their user follows; those without a feed
+-- apply to all of them.
+SELECT rules.* FROM rules
+INNER JOIN feed_follows
+ON feed_follows.user_id = rules.user_id
+AND feed_follows.feed_id = sqlc.arg(feed_id)
+WHERE rules.feed_id IS NULL
+OR rules.feed_id = sqlc.arg(feed_id)
+ORDER BY rules.user_id, rules.created_at;
+
+-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE user_id = $1
+AND id = $2;
@@ -1,10 +1,11 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5
 )
 RETURNING *;
 
@@ -12,12 +13,36 @@ RETURNING *;
 SELECT * FROM users
 WHERE name = $1;
 
+-- name: GetUserByFeedSecret :one
+SELECT * FROM users
+WHERE feed_secret = $1;
+
+-- name: GetUserByFeverKey :one
+SELECT * FROM users
+WHERE fever_key_hash = $1;
+
 -- name: GetUserFromID :one
 SELECT * FROM users
 WHERE id = $1;
 
 -- name: GetUsers :many
-SELECT * FROM users;
+SELECT * FROM users
+ORDER BY created_at, id;
 
 -- name: Reset :exec
-DELETE FROM users;
\ No newline at end of file
+DELETE FROM users;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserFeedSecret :exec
+UPDATE users
+SET feed_secret = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserFeverKey :exec
+UPDATE users
+SET fever_key_hash = $2, updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,46 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, user_id, url, feed_id, keyword, secret)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetWebhooksForUser :many
+SELECT webhooks.*, feeds.url AS feed_url
+FROM webhooks
+LEFT JOIN feeds
+ON webhooks.feed_id = feeds.id
+WHERE webhooks.user_id = $1
+ORDER BY webhooks.created_at;
+
+-- name: GetWebhooksForFeed :many
+-- Webhooks only fire for feeds their user follows; those without a feed
+-- fire for all of them.
+SELECT webhooks.* FROM webhooks
+INNER JOIN feed_follows
+ON feed_follows.user_id = webhooks.user_id
+AND feed_follows.feed_id = sqlc.arg(feed_id)
+WHERE webhooks.feed_id IS NULL
+OR webhooks.feed_id = sqlc.arg(feed_id)
+ORDER BY webhooks.created_at;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE user_id = sqlc.arg(user_id)
+AND (id::text = sqlc.arg(webhook)::text OR url = sqlc.arg(webhook)::text);
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (id, created_at, webhook_id, feed_id, posts, attempts, status_code, error)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: GetWebhookDeliveriesForUser :many
+SELECT
+    webhook_deliveries.*,
+    webhooks.url AS webhook_url,
+    feeds.name AS feed_name
+FROM webhook_deliveries
+INNER JOIN webhooks
+ON webhook_deliveries.webhook_id = webhooks.id
+INNER JOIN feeds
+ON webhook_deliveries.feed_id = feeds.id
+WHERE webhooks.user_id = $1
+ORDER BY webhook_deliveries.created_at DESC
+LIMIT $2;
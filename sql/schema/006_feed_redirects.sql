@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD deactivated_at TIMESTAMP;
+
+CREATE TABLE feed_aliases (
+    id          UUID        PRIMARY KEY,
+    created_at  TIMESTAMP   NOT NULL,
+    url         TEXT        NOT NULL UNIQUE,
+    feed_id     UUID        NOT NULL REFERENCES  feeds
+                            ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_aliases;
+
+ALTER TABLE feeds
+DROP COLUMN deactivated_at;
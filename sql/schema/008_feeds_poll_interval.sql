@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds 
+ADD poll_interval_seconds INT,
+ADD interval_override_seconds INT;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN poll_interval_seconds,
+DROP COLUMN interval_override_seconds;
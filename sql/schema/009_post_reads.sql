@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE post_reads (
+    user_id     UUID        NOT NULL REFERENCES  users
+                            ON DELETE CASCADE,
+    post_id     UUID        NOT NULL REFERENCES  posts
+                            ON DELETE CASCADE,
+    read_at     TIMESTAMP   NOT NULL,
+    PRIMARY KEY(user_id, post_id)
+);
+
+-- The timeline walks a user's follows (covered by UNIQUE(user_id, feed_id)),
+-- then each followed feed's newest posts, then the user's read marks
+-- (covered by the primary key above).
+CREATE INDEX posts_feed_id_published_at_idx ON posts (feed_id, published_at DESC);
+
+-- Lookups from a feed to its followers.
+CREATE INDEX feed_follows_feed_id_idx ON feed_follows (feed_id);
+
+-- +goose Down
+DROP INDEX feed_follows_feed_id_idx;
+DROP INDEX posts_feed_id_published_at_idx;
+DROP TABLE post_reads;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Titles and descriptions used to be stored through Go's html.EscapeString.
+-- Undo it so posts hold the feed's raw content; &amp; has to go last.
+UPDATE posts
+SET title = replace(replace(replace(replace(replace(title,
+        '&lt;', '<'), '&gt;', '>'), '&#39;', ''''), '&#34;', '"'), '&amp;', '&'),
+    description = replace(replace(replace(replace(replace(description,
+        '&lt;', '<'), '&gt;', '>'), '&#39;', ''''), '&#34;', '"'), '&amp;', '&');
+
+-- +goose Down
+UPDATE posts
+SET title = replace(replace(replace(replace(replace(title,
+        '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '''', '&#39;'), '"', '&#34;'),
+    description = replace(replace(replace(replace(replace(description,
+        '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '''', '&#39;'), '"', '&#34;');
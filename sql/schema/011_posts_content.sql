@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE posts
+ADD content_html TEXT,
+ADD content_text TEXT;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN content_html,
+DROP COLUMN content_text;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE post_stars (
+    user_id     UUID        NOT NULL REFERENCES  users
+                            ON DELETE CASCADE,
+    post_id     UUID        NOT NULL REFERENCES  posts
+                            ON DELETE CASCADE,
+    starred_at  TIMESTAMP   NOT NULL,
+    PRIMARY KEY(user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE post_stars;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE users
+ADD password_hash TEXT;
+
+CREATE TABLE api_tokens (
+    id              UUID        PRIMARY KEY,
+    created_at      TIMESTAMP   NOT NULL,
+    user_id         UUID        NOT NULL REFERENCES  users
+                                ON DELETE CASCADE,
+    name            TEXT        NOT NULL,
+    -- tokens are shown once; only their SHA-256 is kept
+    token_hash      TEXT        UNIQUE NOT NULL,
+    token_prefix    TEXT        NOT NULL,
+    last_used_at    TIMESTAMP,
+    UNIQUE(user_id, name)
+);
+
+-- +goose Down
+DROP TABLE api_tokens;
+
+ALTER TABLE users
+DROP COLUMN password_hash;
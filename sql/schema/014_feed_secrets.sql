@@ -0,0 +1,9 @@
+-- +goose Up
+-- the unguessable part of a user's timeline feed URL, kept in the clear so
+-- the URL can be shown again
+ALTER TABLE users
+ADD feed_secret TEXT UNIQUE;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN feed_secret;
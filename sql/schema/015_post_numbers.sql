@@ -0,0 +1,8 @@
+-- +goose Up
+-- a small integer id for APIs whose clients can't handle UUIDs
+ALTER TABLE posts
+ADD number BIGINT NOT NULL GENERATED ALWAYS AS IDENTITY UNIQUE;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN number;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Fever clients know feeds by small integers
+ALTER TABLE feeds
+ADD number BIGINT NOT NULL GENERATED ALWAYS AS IDENTITY UNIQUE;
+
+-- the SHA-256 of the user's Fever API key, itself the MD5 of
+-- "name:password"
+ALTER TABLE users
+ADD fever_key_hash TEXT UNIQUE;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN fever_key_hash;
+
+ALTER TABLE feeds
+DROP COLUMN number;
@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE webhooks (
+    id          UUID        PRIMARY KEY,
+    created_at  TIMESTAMP   NOT NULL,
+    user_id     UUID        NOT NULL REFERENCES  users
+                            ON DELETE CASCADE,
+    url         TEXT        NOT NULL,
+    -- without a feed, posts from every feed the user follows are sent
+    feed_id     UUID        REFERENCES  feeds
+                            ON DELETE CASCADE,
+    -- only posts whose title or text contain it are sent
+    keyword     TEXT,
+    -- deliveries are signed with HMAC-SHA256, so it's kept as is
+    secret      TEXT        NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+    id          UUID        PRIMARY KEY,
+    created_at  TIMESTAMP   NOT NULL,
+    webhook_id  UUID        NOT NULL REFERENCES  webhooks
+                            ON DELETE CASCADE,
+    feed_id     UUID        NOT NULL REFERENCES  feeds
+                            ON DELETE CASCADE,
+    posts       INTEGER     NOT NULL,
+    attempts    INTEGER     NOT NULL,
+    status_code INTEGER,
+    -- NULL when the receiver accepted the delivery
+    error       TEXT
+);
+
+CREATE INDEX webhook_deliveries_webhook_id_created_at_idx
+ON webhook_deliveries (webhook_id, created_at);
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+
+DROP TABLE webhooks;
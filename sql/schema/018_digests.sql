@@ -0,0 +1,23 @@
+-- +goose Up
+-- unlike the other tables, the timestamps here are in UTC
+CREATE TABLE digests (
+    user_id         UUID        PRIMARY KEY REFERENCES  users
+                                ON DELETE CASCADE,
+    created_at      TIMESTAMP   NOT NULL,
+    updated_at      TIMESTAMP   NOT NULL,
+    email           TEXT        NOT NULL,
+    frequency       TEXT        NOT NULL CHECK (frequency IN ('daily', 'weekly')),
+    -- an IANA name such as Europe/Berlin; send_minute and weekday are
+    -- in it
+    time_zone       TEXT        NOT NULL,
+    send_minute     INTEGER     NOT NULL,
+    -- 0 is Sunday, only weekly digests use it
+    weekday         INTEGER     NOT NULL,
+    last_sent_at    TIMESTAMP,
+    next_send_at    TIMESTAMP   NOT NULL
+);
+
+CREATE INDEX digests_next_send_at_idx ON digests (next_send_at);
+
+-- +goose Down
+DROP TABLE digests;
@@ -0,0 +1,53 @@
+-- +goose Up
+-- rules can match on who wrote a post and what it's filed under
+ALTER TABLE posts
+ADD author TEXT,
+ADD categories TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE rules (
+    id          UUID        PRIMARY KEY,
+    created_at  TIMESTAMP   NOT NULL,
+    user_id     UUID        NOT NULL REFERENCES  users
+                            ON DELETE CASCADE,
+    -- without a feed, the rule is for every feed the user follows
+    feed_id     UUID        REFERENCES  feeds
+                            ON DELETE CASCADE,
+    field       TEXT        NOT NULL CHECK (field IN ('any', 'title', 'content', 'author', 'category')),
+    pattern     TEXT        NOT NULL,
+    -- a regular expression rather than a keyword
+    is_regex    BOOLEAN     NOT NULL,
+    action      TEXT        NOT NULL CHECK (action IN ('hide', 'read', 'star', 'tag', 'notify')),
+    -- the tag the tag action adds
+    tag         TEXT,
+    CHECK ((action = 'tag') = (tag IS NOT NULL))
+);
+
+CREATE TABLE post_tags (
+    user_id     UUID        NOT NULL REFERENCES  users
+                            ON DELETE CASCADE,
+    post_id     UUID        NOT NULL REFERENCES  posts
+                            ON DELETE CASCADE,
+    tag         TEXT        NOT NULL,
+    tagged_at   TIMESTAMP   NOT NULL,
+    PRIMARY KEY(user_id, post_id, tag)
+);
+
+CREATE TABLE post_hides (
+    user_id     UUID        NOT NULL REFERENCES  users
+                            ON DELETE CASCADE,
+    post_id     UUID        NOT NULL REFERENCES  posts
+                            ON DELETE CASCADE,
+    hidden_at   TIMESTAMP   NOT NULL,
+    PRIMARY KEY(user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE post_hides;
+
+DROP TABLE post_tags;
+
+DROP TABLE rules;
+
+ALTER TABLE posts
+DROP COLUMN categories,
+DROP COLUMN author;
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const defaultTerminalWidth = 80
+
+// stdinLines reads piped input line by line across calls.
+var stdinLines = bufio.NewReader(os.Stdin)
+
+// terminalWidth returns the width of stdout if it is a terminal, then
+// $COLUMNS, then a sensible default.
+func terminalWidth() int {
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return defaultTerminalWidth
+}
+
+// useColor decides whether to print ANSI styling for a --color mode of
+// "always", "never" or "auto". Auto styles terminals unless $NO_COLOR is set.
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto", "":
+		return os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(os.Stdout.Fd())), nil
+	}
+	return false, newUsageError("unknown color mode %q, expected auto, always or never", mode)
+}
+
+// page shows text through $PAGER, or less, when w is a terminal and writes
+// it out directly otherwise.
+func page(w *os.File, text string) error {
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if len(pager) == 0 {
+		pager = []string{"less"}
+	}
+	if !term.IsTerminal(int(w.Fd())) {
+		_, err := io.WriteString(w, text)
+		return err
+	}
+
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout, cmd.Stderr = w, os.Stderr
+	if os.Getenv("LESS") == "" {
+		// keep colors, and don't page what fits on one screen
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	err := cmd.Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		_, err = io.WriteString(w, text)
+	}
+	return err
+}
+
+// readPassword asks for a password without echoing it when stdin is a
+// terminal, and otherwise reads a line of stdin so scripts can pipe one in.
+func readPassword(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := stdinLines.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			return "", fmt.Errorf("reading password: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(password), err
+}
@@ -0,0 +1,694 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/jamistoso/gator/internal/htmltext"
+	"golang.org/x/term"
+)
+
+const (
+	defaultTUIRefresh = 10 * time.Second
+	defaultTUILimit   = 200
+
+	// the smallest terminal the three panes fit in
+	tuiMinWidth  = 40
+	tuiMinHeight = 8
+
+	tuiHelp = "q quit  tab pane  j/k move  enter read  n next unread  m un/read  s star  o open  r refresh"
+)
+
+type tuiPane int
+
+const (
+	paneFeeds tuiPane = iota
+	panePosts
+	paneBody
+)
+
+// tui is the interactive reader: followed feeds on the left, the selected
+// feed's posts top right and the selected post below them.
+type tui struct {
+	s     *state
+	user  database.User
+	color bool
+	limit int32
+
+	fd       int
+	rawState *term.State
+
+	// feeds holds only followed feeds. feedIdx 0 is the combined timeline,
+	// shown as feed uuid.Nil, so feedIdx i > 0 is feeds[i-1].
+	feeds     []database.GetFollowedFeedsWithUnreadCountsRow
+	feedID    uuid.UUID
+	feedIdx   int
+	feedTop   int
+	postCount int64
+
+	posts   []database.GetPostsForUserRow
+	postIdx int
+	postTop int
+
+	// body is the selected post rendered for bodyWidth columns.
+	body      []string
+	bodyPost  uuid.UUID
+	bodyWidth int
+	bodyTop   int
+
+	focus  tuiPane
+	status string
+
+	width, height int
+	feedsWidth    int
+	postRows      int
+	bodyRows      int
+}
+
+func handlerTUI(s *state, cmd command, currentUser database.User) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("tui needs an interactive terminal")
+	}
+	refresh := cmd.durationFlag("refresh")
+	if refresh <= 0 {
+		return newUsageError("tui: --refresh must be a positive duration such as 10s")
+	}
+	limit := cmd.intFlag("limit")
+	if limit < 1 {
+		return newUsageError("tui: --limit must be a positive number, got %d", limit)
+	}
+	color, err := useColor(cmd.stringFlag("color"))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	t := &tui{
+		s:     s,
+		user:  currentUser,
+		color: color,
+		limit: int32(limit),
+		fd:    fd,
+	}
+	if _, err := t.loadFeeds(ctx); err != nil {
+		return err
+	}
+	if err := t.loadPosts(ctx); err != nil {
+		return err
+	}
+
+	if err := t.enterScreen(); err != nil {
+		return err
+	}
+	defer t.leaveScreen()
+	return t.run(ctx, refresh)
+}
+
+// enterScreen switches to the alternate screen in raw mode.
+func (t *tui) enterScreen() error {
+	rawState, err := term.MakeRaw(t.fd)
+	if err != nil {
+		return err
+	}
+	t.rawState = rawState
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	return nil
+}
+
+// leaveScreen gives the terminal back the way it was.
+func (t *tui) leaveScreen() {
+	fmt.Print("\x1b[?25h\x1b[?1049l")
+	term.Restore(t.fd, t.rawState)
+}
+
+func (t *tui) run(ctx context.Context, refresh time.Duration) error {
+	keys := make(chan []string)
+	handled := make(chan struct{})
+	go readKeys(os.Stdin, keys, handled)
+
+	resized := make(chan os.Signal, 1)
+	if len(resizeSignals) > 0 {
+		signal.Notify(resized, resizeSignals...)
+		defer signal.Stop(resized)
+	}
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	for {
+		t.draw()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-resized:
+		case <-ticker.C:
+			t.refresh(ctx)
+		case batch, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, key := range batch {
+				if quit := t.handleKey(ctx, key); quit {
+					return nil
+				}
+			}
+			handled <- struct{}{}
+		}
+	}
+}
+
+// readKeys hands over the keys read from the terminal in batches, and waits
+// for each batch to be handled before reading on so that a browser started
+// for a key has the terminal to itself.
+func readKeys(r io.Reader, keys chan<- []string, handled <-chan struct{}) {
+	buf := make([]byte, 256)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		keys <- parseKeys(buf[:n])
+		<-handled
+	}
+}
+
+var escapeKeys = map[string]string{
+	"\x1b[A":  "up",
+	"\x1b[B":  "down",
+	"\x1b[C":  "right",
+	"\x1b[D":  "left",
+	"\x1bOA":  "up",
+	"\x1bOB":  "down",
+	"\x1bOC":  "right",
+	"\x1bOD":  "left",
+	"\x1b[H":  "home",
+	"\x1b[F":  "end",
+	"\x1b[1~": "home",
+	"\x1b[4~": "end",
+	"\x1b[5~": "pgup",
+	"\x1b[6~": "pgdown",
+	"\x1b[Z":  "backtab",
+}
+
+// parseKeys names the keys in a chunk of terminal input. Printable keys are
+// named by themselves.
+func parseKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		if b[0] == 0x1b {
+			key, size := parseEscape(b)
+			if key != "" {
+				keys = append(keys, key)
+			}
+			b = b[size:]
+			continue
+		}
+		switch b[0] {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case '\t':
+			keys = append(keys, "tab")
+		case 0x03:
+			keys = append(keys, "ctrl-c")
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys = append(keys, string(r))
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// parseEscape reads the escape sequence b starts with. Sequences we don't
+// know are swallowed whole so their bytes aren't taken for keys.
+func parseEscape(b []byte) (string, int) {
+	for seq, key := range escapeKeys {
+		if strings.HasPrefix(string(b), seq) {
+			return key, len(seq)
+		}
+	}
+	if len(b) < 2 || b[1] != '[' {
+		return "esc", 1
+	}
+	for i := 2; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			return "", i + 1
+		}
+	}
+	return "", len(b)
+}
+
+// handleKey acts on a key and reports whether to quit.
+func (t *tui) handleKey(ctx context.Context, key string) bool {
+	t.status = ""
+	switch key {
+	case "q", "ctrl-c":
+		return true
+	case "tab", "right", "l":
+		t.focus = min(t.focus+1, paneBody)
+	case "backtab", "left", "h":
+		t.focus = max(t.focus-1, paneFeeds)
+	case "down", "j":
+		t.move(ctx, 1)
+	case "up", "k":
+		t.move(ctx, -1)
+	case "pgdown", " ":
+		t.move(ctx, t.pageSize())
+	case "pgup", "b":
+		t.move(ctx, -t.pageSize())
+	case "home", "g":
+		t.move(ctx, -1<<30)
+	case "end", "G":
+		t.move(ctx, 1<<30)
+	case "enter":
+		switch t.focus {
+		case paneFeeds:
+			t.focus = panePosts
+		case panePosts:
+			if t.selectedPost() != nil {
+				t.focus = paneBody
+				t.setRead(ctx, true)
+			}
+		}
+	case "n":
+		t.nextUnread(ctx)
+	case "m":
+		if post := t.selectedPost(); post != nil {
+			t.setRead(ctx, !post.IsRead)
+		}
+	case "s":
+		t.toggleStar(ctx)
+	case "o":
+		t.openSelected(ctx)
+	case "r":
+		t.refresh(ctx)
+		if t.status == "" {
+			t.status = "Refreshed"
+		}
+	}
+	return false
+}
+
+func (t *tui) pageSize() int {
+	if t.focus == panePosts {
+		return max(t.postRows-1, 1)
+	}
+	return max(t.bodyRows-1, 1)
+}
+
+// move goes down (or up, for negative steps) in the focused pane.
+func (t *tui) move(ctx context.Context, step int) {
+	switch t.focus {
+	case paneFeeds:
+		idx := clamp(t.feedIdx+step, 0, len(t.feeds))
+		if idx == t.feedIdx {
+			return
+		}
+		t.feedIdx = idx
+		t.feedID = uuid.Nil
+		if idx > 0 {
+			t.feedID = t.feeds[idx-1].ID
+		}
+		if err := t.loadPosts(ctx); err != nil {
+			t.status = "Error: " + err.Error()
+		}
+	case panePosts:
+		t.postIdx = clamp(t.postIdx+step, 0, len(t.posts)-1)
+	case paneBody:
+		t.bodyTop = clamp(t.bodyTop+step, 0, len(t.body)-t.bodyRows)
+	}
+}
+
+func (t *tui) selectedPost() *database.GetPostsForUserRow {
+	if t.postIdx < 0 || t.postIdx >= len(t.posts) {
+		return nil
+	}
+	return &t.posts[t.postIdx]
+}
+
+func (t *tui) nextUnread(ctx context.Context) {
+	for i := t.postIdx + 1; i < len(t.posts); i++ {
+		if !t.posts[i].IsRead {
+			t.postIdx = i
+			t.focus = paneBody
+			t.setRead(ctx, true)
+			return
+		}
+	}
+	t.status = "No more unread posts"
+}
+
+func (t *tui) setRead(ctx context.Context, read bool) {
+	post := t.selectedPost()
+	if post == nil || post.IsRead == read {
+		return
+	}
+	var err error
+	if read {
+		err = t.s.db.MarkPostRead(ctx, database.MarkPostReadParams{
+			UserID: t.user.ID,
+			PostID: post.ID,
+			ReadAt: time.Now(),
+		})
+	} else {
+		err = t.s.db.MarkPostUnread(ctx, database.MarkPostUnreadParams{
+			UserID: t.user.ID,
+			PostID: post.ID,
+		})
+	}
+	if err != nil {
+		t.status = "Error: " + err.Error()
+		return
+	}
+	post.IsRead = read
+	t.refresh(ctx)
+}
+
+func (t *tui) toggleStar(ctx context.Context) {
+	post := t.selectedPost()
+	if post == nil {
+		return
+	}
+	var err error
+	if post.IsStarred {
+		err = t.s.db.UnstarPost(ctx, database.UnstarPostParams{
+			UserID: t.user.ID,
+			PostID: post.ID,
+		})
+	} else {
+		err = t.s.db.StarPost(ctx, database.StarPostParams{
+			UserID:    t.user.ID,
+			PostID:    post.ID,
+			StarredAt: time.Now(),
+		})
+	}
+	if err != nil {
+		t.status = "Error: " + err.Error()
+		return
+	}
+	post.IsStarred = !post.IsStarred
+}
+
+// openSelected hands the terminal to the browser while it shows the post.
+func (t *tui) openSelected(ctx context.Context) {
+	post := t.selectedPost()
+	if post == nil {
+		return
+	}
+	t.leaveScreen()
+	err := openInBrowser(post.Url)
+	if rawErr := t.enterScreen(); rawErr != nil {
+		err = errors.Join(err, rawErr)
+	}
+	if err != nil {
+		t.status = fmt.Sprintf("Couldn't open %s: %v", post.Url, err)
+		return
+	}
+	t.setRead(ctx, true)
+}
+
+// refresh reloads the unread counts, and the posts too if agg has added
+// some since the last look or the feed list changed.
+func (t *tui) refresh(ctx context.Context) {
+	feedID := t.feedID
+	added, err := t.loadFeeds(ctx)
+	if err == nil && (added != 0 || t.feedID != feedID) {
+		err = t.loadPosts(ctx)
+	}
+	switch {
+	case err != nil:
+		t.status = "Error: " + err.Error()
+	case added == 1:
+		t.status = "1 new post"
+	case added > 1:
+		t.status = fmt.Sprintf("%d new posts", added)
+	}
+}
+
+// loadFeeds reloads the followed feeds and reports how many posts they
+// gained since the last load.
+func (t *tui) loadFeeds(ctx context.Context) (int64, error) {
+	feeds, err := t.s.db.GetFollowedFeedsWithUnreadCounts(ctx, uuid.NullUUID{
+		UUID:  t.user.ID,
+		Valid: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, feed := range feeds {
+		total += feed.PostCount
+	}
+	added := total - t.postCount
+	t.feeds, t.postCount = feeds, total
+
+	// the selected feed may have been unfollowed meanwhile
+	t.feedIdx = 0
+	for i, feed := range feeds {
+		if feed.ID == t.feedID {
+			t.feedIdx = i + 1
+		}
+	}
+	if t.feedIdx == 0 {
+		t.feedID = uuid.Nil
+	}
+	return added, nil
+}
+
+// loadPosts reloads the selected feed's posts, keeping the selected post.
+func (t *tui) loadPosts(ctx context.Context) error {
+	params := database.GetPostsForUserParams{
+		UserID: uuid.NullUUID{
+			UUID:  t.user.ID,
+			Valid: true,
+		},
+		SortBy:   "published",
+		RowLimit: t.limit,
+	}
+	if t.feedIdx > 0 {
+		params.Feed = sql.NullString{String: t.feeds[t.feedIdx-1].Url, Valid: true}
+	}
+	posts, err := t.s.db.GetPostsForUser(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	selected := uuid.Nil
+	if post := t.selectedPost(); post != nil {
+		selected = post.ID
+	}
+	t.posts, t.postIdx = posts, 0
+	for i, post := range posts {
+		if post.ID == selected {
+			t.postIdx = i
+		}
+	}
+	return nil
+}
+
+// layout sizes the panes for the current terminal.
+func (t *tui) layout() {
+	if width, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		t.width, t.height = width, height
+	}
+	t.feedsWidth = clamp(t.width/4, 16, 40)
+	rows := t.height - 1
+	t.postRows = max(rows/3, 3)
+	t.bodyRows = rows - t.postRows - 1
+}
+
+func (t *tui) draw() {
+	t.layout()
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	if t.width < tuiMinWidth || t.height < tuiMinHeight {
+		b.WriteString("\x1b[2J")
+		b.WriteString(htmltext.Fit("Terminal too small", t.width))
+		os.Stdout.WriteString(b.String())
+		return
+	}
+
+	rightWidth := t.width - t.feedsWidth - 1
+	feedLines := t.feedLines(t.height - 1)
+	postLines := t.postLines(rightWidth)
+	bodyLines := t.bodyLines(rightWidth)
+
+	position := ""
+	if len(t.posts) > 0 {
+		position = fmt.Sprintf(" %d/%d ", t.postIdx+1, len(t.posts))
+	}
+	separator := "──" + position + strings.Repeat("─", max(rightWidth-2-len(position), 0))
+
+	for y := 0; y < t.height-1; y++ {
+		fmt.Fprintf(&b, "\x1b[%d;1H", y+1)
+		b.WriteString(feedLines[y])
+		b.WriteString("│")
+		switch {
+		case y < t.postRows:
+			b.WriteString(postLines[y])
+		case y == t.postRows:
+			b.WriteString(separator)
+		default:
+			b.WriteString(bodyLines[y-t.postRows-1])
+		}
+	}
+
+	status := t.status
+	if status == "" {
+		status = tuiHelp
+	}
+	fmt.Fprintf(&b, "\x1b[%d;1H\x1b[7m%s\x1b[0m", t.height, htmltext.Fit(status, t.width))
+	os.Stdout.WriteString(b.String())
+}
+
+func (t *tui) feedLines(rows int) []string {
+	width := t.feedsWidth
+	var totalUnread int64
+	for _, feed := range t.feeds {
+		totalUnread += feed.UnreadCount
+	}
+	entries := []string{feedEntry("All feeds", totalUnread, width)}
+	for _, feed := range t.feeds {
+		name := feed.Name
+		if feed.DeactivatedAt.Valid {
+			name += " (gone)"
+		}
+		entries = append(entries, feedEntry(name, feed.UnreadCount, width))
+	}
+	t.feedTop = scrollTo(t.feedTop, t.feedIdx, rows)
+	return t.listLines(entries, t.feedIdx, t.feedTop, rows, width, t.focus == paneFeeds)
+}
+
+// feedEntry puts a feed's unread count after its name.
+func feedEntry(name string, unread int64, width int) string {
+	count := ""
+	if unread > 0 {
+		count = strconv.FormatInt(unread, 10)
+	}
+	return htmltext.Fit(name, width-6) + fmt.Sprintf(" %5s", count)
+}
+
+func (t *tui) postLines(width int) []string {
+	if len(t.posts) == 0 {
+		lines := make([]string, t.postRows)
+		for i := range lines {
+			lines[i] = strings.Repeat(" ", width)
+		}
+		lines[0] = htmltext.Fit("No posts yet. Follow feeds with 'gator follow <url>' and run 'gator agg'.", width)
+		return lines
+	}
+
+	entries := make([]string, len(t.posts))
+	for i, post := range t.posts {
+		readMark, starMark := " ", " "
+		if !post.IsRead {
+			readMark = "*"
+		}
+		if post.IsStarred {
+			starMark = "!"
+		}
+		entry := fmt.Sprintf("%s%s %-6s %s", readMark, starMark, post.PublishedAt.Time.Format("Jan 02"), htmltext.Line(post.Title.String))
+		if t.feedIdx == 0 {
+			entry += " (" + post.FeedName + ")"
+		}
+		entries[i] = entry
+	}
+	t.postTop = scrollTo(t.postTop, t.postIdx, t.postRows)
+	return t.listLines(entries, t.postIdx, t.postTop, t.postRows, width, t.focus == panePosts)
+}
+
+// listLines fits the visible entries of a list to its pane, highlighting the
+// selected one.
+func (t *tui) listLines(entries []string, selected, top, rows, width int, focused bool) []string {
+	lines := make([]string, rows)
+	for y := range lines {
+		i := top + y
+		if i >= len(entries) {
+			lines[y] = strings.Repeat(" ", width)
+			continue
+		}
+		line := htmltext.Fit(entries[i], width)
+		switch {
+		case i == selected && focused:
+			line = "\x1b[7m" + line + "\x1b[0m"
+		case i == selected:
+			line = "\x1b[1m" + line + "\x1b[0m"
+		}
+		lines[y] = line
+	}
+	return lines
+}
+
+func (t *tui) bodyLines(width int) []string {
+	post := t.selectedPost()
+	switch {
+	case post == nil:
+		t.body, t.bodyPost = nil, uuid.Nil
+	case post.ID != t.bodyPost || width != t.bodyWidth:
+		t.body = renderPostBody(*post, width, t.color)
+		t.bodyPost, t.bodyWidth, t.bodyTop = post.ID, width, 0
+	}
+	t.bodyTop = clamp(t.bodyTop, 0, len(t.body)-t.bodyRows)
+
+	lines := make([]string, t.bodyRows)
+	for y := range lines {
+		line := ""
+		if i := t.bodyTop + y; i < len(t.body) {
+			line = t.body[i]
+		}
+		lines[y] = htmltext.Fit(line, width)
+	}
+	return lines
+}
+
+// renderPostBody lays out a post's heading and content for the body pane.
+func renderPostBody(post database.GetPostsForUserRow, width int, color bool) []string {
+	title := htmltext.Line(post.Title.String)
+	if color {
+		title = "\x1b[1m" + title + "\x1b[0m"
+	}
+	lines := []string{
+		title,
+		post.FeedName + " · " + post.PublishedAt.Time.Format(time.RFC1123),
+		post.Url,
+		"",
+	}
+	text := htmltext.Render(postContent(post.Description, post.ContentHtml), htmltext.Options{
+		Width: width,
+		Color: color,
+	})
+	return append(lines, strings.Split(text, "\n")...)
+}
+
+// scrollTo returns the first row to show so that the selected row is visible.
+func scrollTo(top, selected, rows int) int {
+	if selected < top {
+		return selected
+	}
+	if selected >= top+rows {
+		return selected - rows + 1
+	}
+	return top
+}
+
+// clamp limits n to [lo, hi], preferring lo when the range is empty.
+func clamp(n, lo, hi int) int {
+	return max(lo, min(n, hi))
+}
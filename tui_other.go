@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// resizeSignals is empty where terminals don't signal resizes; the reader
+// picks up the new size on its next redraw.
+var resizeSignals []os.Signal
@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// resizeSignals tell the reader to redraw for a new terminal size.
+var resizeSignals = []os.Signal{syscall.SIGWINCH}
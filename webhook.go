@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jamistoso/gator/internal/database"
+	"github.com/jamistoso/gator/internal/htmltext"
+	"github.com/jamistoso/gator/internal/output"
+	"github.com/jamistoso/gator/internal/webhook"
+)
+
+const (
+	webhookUsage = "usage: gator webhook add <url> | list | remove <id|url> | log"
+	// defaultWebhookLogLimit is how many deliveries webhook log shows.
+	defaultWebhookLogLimit = 20
+	// maxWebhookDeliveries is how many deliveries run at once.
+	maxWebhookDeliveries = 8
+)
+
+var (
+	// webhookClient sends every delivery. Receivers are told where to post
+	// when they register, so redirects are reported rather than followed.
+	webhookClient = &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	// webhookDeliveries tracks deliveries still running in the background.
+	webhookDeliveries sync.WaitGroup
+	webhookSlots      = make(chan struct{}, maxWebhookDeliveries)
+)
+
+func handlerWebhook(s *state, cmd command, currentUser database.User) error {
+	args := cmd.arguments[1:]
+	switch cmd.arguments[0] {
+	case "add":
+		if len(args) != 1 {
+			return newUsageError("webhook add: wrong number of arguments\n%s", webhookUsage)
+		}
+		return handlerWebhookAdd(s, cmd, args[0], currentUser)
+	case "list":
+		if len(args) != 0 {
+			return newUsageError("webhook list: wrong number of arguments\n%s", webhookUsage)
+		}
+		return handlerWebhookList(s, currentUser)
+	case "remove":
+		if len(args) != 1 {
+			return newUsageError("webhook remove: wrong number of arguments\n%s", webhookUsage)
+		}
+		return handlerWebhookRemove(s, args[0], currentUser)
+	case "log":
+		if len(args) != 0 {
+			return newUsageError("webhook log: wrong number of arguments\n%s", webhookUsage)
+		}
+		return handlerWebhookLog(s, cmd, currentUser)
+	default:
+		return newUsageError("webhook: unknown subcommand %s\n%s", cmd.arguments[0], webhookUsage)
+	}
+}
+
+// handlerWebhookAdd registers a URL to send new posts to, from one feed or
+// every feed the user follows, and prints the secret deliveries are signed
+// with.
+func handlerWebhookAdd(s *state, cmd command, address string, currentUser database.User) error {
+	if err := webhook.ValidURL(address); err != nil {
+		return err
+	}
+	var feedID uuid.NullUUID
+	if feedURL := cmd.stringFlag("feed"); feedURL != "" {
+		feed, err := followedFeed(context.Background(), s, currentUser, feedURL)
+		if err != nil {
+			return err
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	var keyword sql.NullString
+	if match := strings.TrimSpace(cmd.stringFlag("match")); match != "" {
+		keyword = sql.NullString{String: match, Valid: true}
+	}
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return err
+	}
+	hook, err := s.db.CreateWebhook(context.Background(), database.CreateWebhookParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    currentUser.ID,
+		Url:       address,
+		FeedID:    feedID,
+		Keyword:   keyword,
+		Secret:    secret,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Added webhook %s. Deliveries carry an %s header signed with this secret; copy it now, it won't be shown again:\n", hook.ID, webhook.SignatureHeader)
+	fmt.Println(secret)
+	return nil
+}
+
+func handlerWebhookList(s *state, currentUser database.User) error {
+	hooks, err := s.db.GetWebhooksForUser(context.Background(), currentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	if s.output != "" {
+		table := output.Table{Columns: []string{"id", "url", "feed", "match", "created_at"}}
+		for _, hook := range hooks {
+			table.Rows = append(table.Rows, []any{
+				hook.ID, hook.Url, hook.FeedUrl, hook.Keyword, hook.CreatedAt,
+			})
+		}
+		return output.Write(os.Stdout, s.output, table)
+	}
+
+	for _, hook := range hooks {
+		feeds := "every feed you follow"
+		if hook.FeedUrl.Valid {
+			feeds = hook.FeedUrl.String
+		}
+		fmt.Printf("%s %s\n  posts from %s", hook.ID, hook.Url, feeds)
+		if hook.Keyword.Valid {
+			fmt.Printf(" matching %q", hook.Keyword.String)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func handlerWebhookRemove(s *state, hook string, currentUser database.User) error {
+	removed, err := s.db.DeleteWebhook(context.Background(), database.DeleteWebhookParams{
+		UserID:  currentUser.ID,
+		Webhook: hook,
+	})
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return fmt.Errorf("webhook not found: %s", hook)
+	}
+	fmt.Printf("Webhook %s removed\n", hook)
+	return nil
+}
+
+// handlerWebhookLog lists the latest deliveries to the user's webhooks,
+// newest first.
+func handlerWebhookLog(s *state, cmd command, currentUser database.User) error {
+	limit := cmd.intFlag("limit")
+	if limit < 1 {
+		return newUsageError("webhook log: --limit must be at least 1")
+	}
+	deliveries, err := s.db.GetWebhookDeliveriesForUser(context.Background(), database.GetWebhookDeliveriesForUserParams{
+		UserID: currentUser.ID,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.output != "" {
+		table := output.Table{Columns: []string{"id", "created_at", "webhook_id", "url", "feed", "posts", "attempts", "status", "error"}}
+		for _, delivery := range deliveries {
+			table.Rows = append(table.Rows, []any{
+				delivery.ID, delivery.CreatedAt, delivery.WebhookID, delivery.WebhookUrl, delivery.FeedName,
+				delivery.Posts, delivery.Attempts, delivery.StatusCode, delivery.Error,
+			})
+		}
+		return output.Write(os.Stdout, s.output, table)
+	}
+
+	for _, delivery := range deliveries {
+		outcome := "delivered"
+		if delivery.Error.Valid {
+			outcome = "failed: " + delivery.Error.String
+		}
+		fmt.Printf("%s %s: %d posts from \"%s\" after %d attempts, %s\n",
+			delivery.CreatedAt.Format(time.DateTime), delivery.WebhookUrl, delivery.Posts,
+			delivery.FeedName, delivery.Attempts, outcome)
+	}
+	return nil
+}
+
+// fireWebhooks starts sending a feed's new posts to the webhooks that want
+// them, one delivery per webhook with every post it matches, without waiting
+// for the receivers; waitForWebhooks does that. Cancelling ctx stops the
+// retries, but the outcome is still logged.
+func fireWebhooks(ctx context.Context, s *state, dbFeed database.Feed, posts []database.UpsertPostRow) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	hooks, err := s.db.GetWebhooksForFeed(context.WithoutCancel(ctx), uuid.NullUUID{UUID: dbFeed.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, hook := range hooks {
+		payload := webhook.Payload{
+			Event: webhook.EventNewPosts,
+			Feed:  webhook.Feed{Name: dbFeed.Name, URL: dbFeed.Url},
+		}
+		for _, post := range posts {
+			if hook.Keyword.Valid && !postMatches(post, hook.Keyword.String) {
+				continue
+			}
+			payload.Posts = append(payload.Posts, webhookPost(post))
+		}
+		if len(payload.Posts) == 0 {
+			continue
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", hook.Url, err))
+			continue
+		}
+
+		webhookDeliveries.Add(1)
+		go func() {
+			defer webhookDeliveries.Done()
+			webhookSlots <- struct{}{}
+			defer func() { <-webhookSlots }()
+			if err := deliverWebhook(ctx, s, hook, dbFeed, len(payload.Posts), body); err != nil {
+				fmt.Println(err)
+			}
+		}()
+	}
+	return errors.Join(errs...)
+}
+
+// deliverWebhook sends one delivery, with its retries, and logs how it went.
+func deliverWebhook(ctx context.Context, s *state, hook database.Webhook, dbFeed database.Feed, posts int, body []byte) error {
+	deliveryID := uuid.New()
+	result := webhook.Deliver(ctx, webhookClient, hook.Url, hook.Secret, deliveryID.String(), body)
+	params := database.CreateWebhookDeliveryParams{
+		ID:        deliveryID,
+		CreatedAt: time.Now(),
+		WebhookID: hook.ID,
+		FeedID:    dbFeed.ID,
+		Posts:     int32(posts),
+		Attempts:  int32(result.Attempts),
+	}
+	if result.StatusCode != 0 {
+		params.StatusCode = sql.NullInt32{Int32: int32(result.StatusCode), Valid: true}
+	}
+	if result.Err != nil {
+		params.Error = sql.NullString{String: result.Err.Error(), Valid: true}
+		fmt.Printf("Webhook %s failed after %d attempts: %v\n", hook.Url, result.Attempts, result.Err)
+	}
+	if err := s.db.CreateWebhookDelivery(context.WithoutCancel(ctx), params); err != nil {
+		return fmt.Errorf("error logging delivery to webhook %s: %w", hook.Url, err)
+	}
+	return nil
+}
+
+// waitForWebhooks waits for the deliveries fireWebhooks started.
+func waitForWebhooks() {
+	webhookDeliveries.Wait()
+}
+
+// postMatches reports whether the post's title or text contains keyword,
+// ignoring case.
+func postMatches(post database.UpsertPostRow, keyword string) bool {
+	keyword = strings.ToLower(keyword)
+	return strings.Contains(strings.ToLower(post.Title.String), keyword) ||
+		strings.Contains(strings.ToLower(post.ContentText.String), keyword)
+}
+
+func webhookPost(post database.UpsertPostRow) webhook.Post {
+	out := webhook.Post{
+		ID:          post.ID.String(),
+		Title:       htmltext.Line(post.Title.String),
+		URL:         post.Url,
+		ContentHTML: post.ContentHtml.String,
+		ContentText: post.ContentText.String,
+	}
+	if post.PublishedAt.Valid {
+		published := post.PublishedAt.Time
+		out.Published = &published
+	}
+	return out
+}